@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/danhale-git/mine/world"
+	"github.com/spf13/cobra"
+)
+
+// negativeNumber matches arguments such as '-5' or '-10,0,-10' which would otherwise be parsed as shorthand flags.
+var negativeNumber = regexp.MustCompile(`^-[0-9]`)
+
+// allowNegativeArgs returns the command line arguments with the positional arguments moved after a '--' separator if
+// any of them are negative numbers, so 'mine get -5 64 3' is not parsed as the shorthand flag '-5'. Command names,
+// flags and flag values are kept in order before the separator. The arguments are returned unchanged if there are no
+// negative positional arguments.
+func allowNegativeArgs(root *cobra.Command, args []string) []string {
+	c, _, err := root.Find(args)
+	if err != nil {
+		return args
+	}
+
+	// The number of command names before the positional arguments
+	names := len(strings.Fields(c.CommandPath())) - 1
+
+	takesValue := func(flag string) bool {
+		name := strings.TrimLeft(flag, "-")
+
+		f := c.Flags().Lookup(name)
+		if f == nil {
+			f = c.InheritedFlags().Lookup(name)
+		}
+		if f == nil && len(name) == 1 && !strings.HasPrefix(flag, "--") {
+			f = c.Flags().ShorthandLookup(name)
+		}
+
+		return f != nil && f.NoOptDefVal == ""
+	}
+
+	flags := make([]string, 0, len(args))
+	positional := make([]string, 0)
+	negative := false
+
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+
+		switch {
+		case a == "--":
+			positional = append(positional, args[i+1:]...)
+			i = len(args)
+		case negativeNumber.MatchString(a):
+			positional = append(positional, a)
+			negative = true
+		case strings.HasPrefix(a, "-") && len(a) > 1:
+			flags = append(flags, a)
+			if !strings.Contains(a, "=") && takesValue(a) && i+1 < len(args) {
+				i++
+				flags = append(flags, args[i])
+			}
+		case names > 0:
+			flags = append(flags, a)
+			names--
+		default:
+			positional = append(positional, a)
+		}
+	}
+
+	if !negative {
+		return args
+	}
+
+	return append(append(flags, "--"), positional...)
+}
+
+// parseBox parses a bounding box given as 'x1,y1,z1,x2,y2,z2'.
+func parseBox(s string) (world.Position, world.Position, error) {
+	c, err := parseInts(s, 6)
+	if err != nil {
+		return world.Position{}, world.Position{}, fmt.Errorf("invalid box '%s': %w", s, err)
+	}
+
+	return world.Position{X: c[0], Y: c[1], Z: c[2]}, world.Position{X: c[3], Y: c[4], Z: c[5]}, nil
+}
+
+// parsePosition parses a position given as 'x,y,z'.
+func parsePosition(s string) (world.Position, error) {
+	c, err := parseInts(s, 3)
+	if err != nil {
+		return world.Position{}, fmt.Errorf("invalid position '%s': %w", s, err)
+	}
+
+	return world.Position{X: c[0], Y: c[1], Z: c[2]}, nil
+}
+
+// parseInts parses n comma separated integers.
+func parseInts(s string, n int) ([]int, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != n {
+		return nil, fmt.Errorf("expected %d comma separated values", n)
+	}
+
+	c := make([]int, n)
+
+	for i, p := range parts {
+		v, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return nil, err
+		}
+		c[i] = v
+	}
+
+	return c, nil
+}
+
+// blockString returns the block id, followed by its states in name order if includeStates is true, in the format
+// 'minecraft:fence[wood_type=oak]'.
+func blockString(b world.Block, includeStates bool) string {
+	if !includeStates || len(b.States) == 0 {
+		return b.ID
+	}
+
+	names := make([]string, 0, len(b.States))
+	for n := range b.States {
+		names = append(names, n)
+	}
+
+	sort.Strings(names)
+
+	states := make([]string, len(names))
+	for i, n := range names {
+		states[i] = fmt.Sprintf("%s=%v", n, b.States[n])
+	}
+
+	return fmt.Sprintf("%s[%s]", b.ID, strings.Join(states, ","))
+}
+
+// blockID returns the id with the 'minecraft:' namespace added if it has none.
+func blockID(id string) string {
+	if !strings.Contains(id, ":") {
+		return "minecraft:" + id
+	}
+
+	return id
+}
+
+// parseStates parses states given as 'name=value'. Values are kept as strings to be compared with stateMatches.
+func parseStates(args []string) (map[string]string, error) {
+	states := make(map[string]string)
+
+	for _, a := range args {
+		parts := strings.SplitN(a, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid state '%s': expected name=value", a)
+		}
+
+		states[parts[0]] = parts[1]
+	}
+
+	return states, nil
+}
+
+// stateMatches reports whether the block has every given state. Boolean states may be given as true/false or 1/0.
+func stateMatches(b world.Block, states map[string]string) bool {
+	for name, want := range states {
+		v, ok := b.States[name]
+		if !ok {
+			return false
+		}
+
+		switch want {
+		case "true":
+			want = "1"
+		case "false":
+			want = "0"
+		}
+
+		if fmt.Sprint(v) != want {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseBlock parses a block given as 'id' or 'id[name=value,...]', returning the id with its namespace and the states
+// as strings.
+func parseBlock(s string) (string, map[string]string, error) {
+	id := s
+	var states []string
+
+	if i := strings.Index(s, "["); i >= 0 {
+		if !strings.HasSuffix(s, "]") {
+			return "", nil, fmt.Errorf("invalid block '%s': expected id[name=value,...]", s)
+		}
+
+		id = s[:i]
+		if inner := s[i+1 : len(s)-1]; inner != "" {
+			states = strings.Split(inner, ",")
+		}
+	}
+
+	if id == "" {
+		return "", nil, fmt.Errorf("invalid block '%s': missing id", s)
+	}
+
+	want, err := parseStates(states)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid block '%s': %w", s, err)
+	}
+
+	return blockID(id), want, nil
+}
+
+// typedStates converts states parsed by parseStates to block state values. Names ending '_bit' and true/false values
+// are byte states, whole numbers are int states and anything else is a string state.
+func typedStates(states map[string]string) map[string]interface{} {
+	typed := make(map[string]interface{}, len(states))
+
+	for name, v := range states {
+		switch {
+		case v == "true" || v == "false":
+			typed[name] = v == "true"
+		case strings.HasSuffix(name, "_bit"):
+			n, err := strconv.ParseInt(v, 10, 8)
+			if err != nil {
+				typed[name] = v
+				continue
+			}
+			typed[name] = int8(n)
+		default:
+			n, err := strconv.ParseInt(v, 10, 32)
+			if err != nil {
+				typed[name] = v
+				continue
+			}
+			typed[name] = int32(n)
+		}
+	}
+
+	return typed
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+func newBiomeCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "biome <x> <y> <z>",
+		Short: "Print the biome at the given coordinates",
+		Long: `Print the biome at the given coordinates. Chunks saved before 1.18 have one biome for each
+column, in which case y is ignored.`,
+		Args: cobra.ExactArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			w := openWorld()
+			defer closeWorld(w)
+
+			b, err := w.GetBiome(atoi(args[0]), atoi(args[1]), atoi(args[2]), dimension)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			fmt.Printf("%s (%d)\n", b, int32(b))
+		},
+	}
+}
+
+func newHeightCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "height <x> <z>",
+		Short: "Print the height map value of the column at the given coordinates",
+		Long: `Print the height map value of the column at the given coordinates. This is the y coordinate
+above the highest block which blocks light, as last calculated by the game.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			w := openWorld()
+			defer closeWorld(w)
+
+			h, err := w.HeightAt(atoi(args[0]), atoi(args[1]), dimension)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			fmt.Println(h)
+		},
+	}
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/danhale-git/mine/nbt"
+	"github.com/spf13/cobra"
+)
+
+func newBlockEntityCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "blockentity",
+		Short: "Print, set or remove block entities",
+		Long: `Print, set or remove the block entity of a block. Block entities hold the extra data of blocks
+such as chest items, sign text and spawner mob types, and are given as json as printed by
+'blockentity get'.`,
+	}
+
+	c.AddCommand(&cobra.Command{
+		Use:   "get <x> <y> <z>",
+		Short: "Print the block entity of a block as json",
+		Args:  cobra.ExactArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			w := openWorld()
+			defer closeWorld(w)
+
+			e, err := w.GetBlockEntity(atoi(args[0]), atoi(args[1]), atoi(args[2]), dimension)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if e == nil {
+				log.Fatalf("block %s %s %s has no block entity", args[0], args[1], args[2])
+			}
+
+			out, err := json.MarshalIndent(e, "", "  ")
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			fmt.Println(string(out))
+		},
+	})
+
+	c.AddCommand(&cobra.Command{
+		Use:   "set <x> <y> <z> [file]",
+		Short: "Set the block entity of a block from json",
+		Long: `Set the block entity of a block from json read from the file, or from stdin if no file is given.
+Any existing block entity is replaced. The coordinates in the json are ignored.`,
+		Args: cobra.RangeArgs(3, 4),
+		Run: func(cmd *cobra.Command, args []string) {
+			var r io.Reader = os.Stdin
+
+			if len(args) == 4 {
+				f, err := os.Open(args[3])
+				if err != nil {
+					log.Fatal(err)
+				}
+				defer f.Close()
+
+				r = f
+			}
+
+			e := nbt.NBTTag{}
+			if err := json.NewDecoder(r).Decode(&e); err != nil {
+				log.Fatalf("decoding block entity: %s", err)
+			}
+
+			w := openWorld()
+			defer closeWorld(w)
+
+			if err := w.SetBlockEntity(atoi(args[0]), atoi(args[1]), atoi(args[2]), dimension, e); err != nil {
+				log.Fatal(err)
+			}
+		},
+	})
+
+	c.AddCommand(&cobra.Command{
+		Use:   "remove <x> <y> <z>",
+		Short: "Remove the block entity of a block",
+		Args:  cobra.ExactArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			w := openWorld()
+			defer closeWorld(w)
+
+			removed, err := w.RemoveBlockEntity(atoi(args[0]), atoi(args[1]), atoi(args[2]), dimension)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if !removed {
+				log.Fatalf("block %s %s %s has no block entity", args[0], args[1], args[2])
+			}
+		},
+	})
+
+	return c
+}
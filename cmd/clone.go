@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+func newCloneCmd() *cobra.Command {
+	var destDimension int
+
+	c := &cobra.Command{
+		Use:   "clone <x1,y1,z1,x2,y2,z2> <x,y,z>",
+		Short: "Copy the blocks in a box to another position",
+		Long: `Copy the blocks in the box, including water logging, so that the lowest corner of the copy is at
+the given position. The copy may overlap the source or be in another dimension.
+
+Blocks in source sub chunks which are not saved are not copied. Destination sub chunks which are
+not saved are created if their chunk is saved, otherwise their blocks are skipped.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			min, max, err := parseBox(args[0])
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			dest, err := parsePosition(args[1])
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if !cmd.Flags().Changed("to-dimension") {
+				destDimension = dimension
+			}
+
+			w := openWorld()
+			defer closeWorld(w)
+
+			n, err := w.Clone(min, max, dimension, dest, destDimension)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			fmt.Printf("copied %d blocks\n", n)
+		},
+	}
+
+	c.Flags().IntVar(&destDimension, "to-dimension", 0, "dimension to copy to, if different from --dimension")
+
+	return c
+}
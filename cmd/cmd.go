@@ -1,8 +1,8 @@
 package cmd
 
 import (
-	"fmt"
 	"log"
+	"os"
 	"path/filepath"
 	"strconv"
 
@@ -10,56 +10,111 @@ import (
 	"github.com/spf13/cobra"
 )
 
-const worldDirPath = `C:\Users\danha\AppData\Local\Packages\Microsoft.MinecraftUWP_8wekyb3d8bbwe\LocalState\games\com.mojang\minecraftWorlds\`
+// Environment variables holding the default world and minecraftWorlds directory.
+const (
+	worldEnv     = "MINE_WORLD"
+	worldsDirEnv = "MINE_WORLDS_DIR"
+)
 
-//const worldFileName = `VsgSYaaGAAA=` // MINETEST  16 64 16
-const worldFileName = `97caYQjdAgA=` // MINETESTFLAT 0 0 0
+// Flags shared by all commands
+var (
+	worldPath string
+	worldsDir string
+	dimension int
+)
 
 func Init() error {
 	root := &cobra.Command{
-		Use:  "mine <x> <y> <z>",
-		Args: cobra.MinimumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			w, err := world.New(filepath.Join(worldDirPath, worldFileName))
-			if err != nil {
-				log.Fatal(err)
-			}
-
-			b, err := w.GetBlock(
-				atoi(args[0]),
-				atoi(args[1]),
-				atoi(args[2]),
-				0,
-			)
-			if err != nil {
-				log.Fatal(err)
-			}
-
-			fmt.Println(b)
-
-			/*c, err := strconv.Atoi(args[0])
-			if err != nil {
-				log.Fatalf("invalid argument '%s': %s", args[0], err)
-			}
-
-			i := 0
-			for x := 0; x < 16; x++ {
-				for z := 0; z < 16; z++ {
-					for y := 0; y < 16; y++ {
-						if i > c {
-							return
-						}
-						fmt.Println(w.GetBlock(x, y, z, 0))
-						i++
-					}
-				}
-			}*/
-		},
+		Use:   "mine",
+		Short: "Read and edit Minecraft Bedrock worlds",
+		Long: `Read and edit Minecraft Bedrock worlds. The world is the world directory containing the 'db'
+folder, given with --world or the ` + worldEnv + ` environment variable. If it is not a directory it
+is taken to be the display name of a world in the minecraftWorlds directory, as listed by 'worlds'.
+
+Minecraft must not have the world open while it is being edited.`,
+		SilenceUsage: true,
 	}
 
+	root.PersistentFlags().StringVar(&worldPath, "world", os.Getenv(worldEnv), "path of the world directory, defaults to $"+worldEnv)
+	root.PersistentFlags().StringVar(&worldsDir, "worlds-dir", defaultWorldsDir(), "path of the minecraftWorlds directory, defaults to $"+worldsDirEnv)
+	root.PersistentFlags().IntVar(&dimension, "dimension", 0, "dimension: 0 overworld, 1 nether, 2 end")
+
+	root.AddCommand(newWorldsCmd())
+	root.AddCommand(newLevelCmd())
+	root.AddCommand(newGameRuleCmd())
+	root.AddCommand(newGetCmd())
+	root.AddCommand(newBiomeCmd())
+	root.AddCommand(newHeightCmd())
+	root.AddCommand(newBlockEntityCmd())
+	root.AddCommand(newEntitiesCmd())
+	root.AddCommand(newKeysCmd())
+	root.AddCommand(newDumpCmd())
+	root.AddCommand(newCountCmd())
+	root.AddCommand(newFindCmd())
+	root.AddCommand(newReplaceCmd())
+	root.AddCommand(newFillCmd())
+	root.AddCommand(newCloneCmd())
+
+	root.SetArgs(allowNegativeArgs(root, os.Args[1:]))
+
 	return root.Execute()
 }
 
+// openWorld opens the world given by --world or exits. The world must be closed with closeWorld.
+func openWorld() *world.World {
+	w, err := world.New(worldDir())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return w
+}
+
+// closeWorld closes a world opened by openWorld, exiting if it can't be closed.
+func closeWorld(w *world.World) {
+	if err := w.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// worldDir returns the path of the world directory given by --world or exits.
+func worldDir() string {
+	if worldPath == "" {
+		log.Fatalf("no world given: use --world or set %s", worldEnv)
+	}
+
+	if info, err := os.Stat(worldPath); err == nil && info.IsDir() {
+		return worldPath
+	}
+
+	if worldsDir == "" {
+		log.Fatalf("world '%s' is not a directory and no minecraftWorlds directory is set: use --worlds-dir or set %s",
+			worldPath, worldsDirEnv)
+	}
+
+	info, err := world.FindWorld(worldsDir, worldPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return info.Path
+}
+
+// defaultWorldsDir returns the value of the worlds directory environment variable, or on Windows the directory where
+// Minecraft stores its worlds.
+func defaultWorldsDir() string {
+	if dir := os.Getenv(worldsDirEnv); dir != "" {
+		return dir
+	}
+
+	if local := os.Getenv("LOCALAPPDATA"); local != "" {
+		return filepath.Join(local, "Packages", "Microsoft.MinecraftUWP_8wekyb3d8bbwe", "LocalState", "games", "com.mojang",
+			"minecraftWorlds")
+	}
+
+	return ""
+}
+
 func atoi(s string) int {
 	i, err := strconv.Atoi(s)
 	if err != nil {
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/danhale-git/mine/world"
+	"github.com/spf13/cobra"
+)
+
+// blockCount is the number of blocks with a given id, and states if they are counted.
+type blockCount struct {
+	Block string `json:"block"`
+	Count int    `json:"count"`
+}
+
+func newCountCmd() *cobra.Command {
+	var box string
+	var states bool
+	var format string
+
+	c := &cobra.Command{
+		Use:   "count",
+		Short: "Count blocks by id across a dimension or bounding box",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			w := openWorld()
+			defer closeWorld(w)
+
+			var counts map[string]int
+
+			if box != "" {
+				min, max, err := parseBox(box)
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				if counts, err = countRegion(w, min, max, dimension, states); err != nil {
+					log.Fatal(err)
+				}
+			} else {
+				var err error
+				if counts, err = countDimension(w, dimension, states); err != nil {
+					log.Fatal(err)
+				}
+			}
+
+			if err := writeCounts(os.Stdout, sortCounts(counts), format); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	c.Flags().StringVar(&box, "box", "", "only count blocks in the box 'x1,y1,z1,x2,y2,z2'")
+	c.Flags().BoolVar(&states, "states", false, "count each combination of block states separately")
+	c.Flags().StringVar(&format, "format", "table", "output format: table, csv or json")
+
+	return c
+}
+
+// countDimension counts the blocks in every stored sub chunk of the dimension.
+func countDimension(w *world.World, dimension int, states bool) (map[string]int, error) {
+	mu := sync.Mutex{}
+	counts := make(map[string]int)
+
+	err := w.Scan(context.Background(), dimension, world.ScanConfig{}, func(s *world.SubChunk) error {
+		// Count locally so the lock is only taken once per sub chunk
+		local := make(map[string]int)
+
+		_ = s.Blocks(func(b world.Block) error {
+			local[blockString(b, states)]++
+			return nil
+		})
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		for k, v := range local {
+			counts[k] += v
+		}
+
+		return nil
+	})
+
+	return counts, err
+}
+
+// countRegion counts the blocks in the box between the given corners. Blocks in sub chunks which are not saved are
+// not counted.
+func countRegion(w *world.World, min, max world.Position, dimension int, states bool) (map[string]int, error) {
+	r, err := w.GetRegion(min, max, dimension)
+	if err != nil {
+		return nil, err
+	}
+
+	perPalette := make([]int, len(r.Palette))
+
+	for _, i := range r.Indices {
+		if i != world.NotSaved {
+			perPalette[i]++
+		}
+	}
+
+	counts := make(map[string]int)
+
+	for i, n := range perPalette {
+		if n > 0 {
+			counts[blockString(r.Palette[i], states)] += n
+		}
+	}
+
+	return counts, nil
+}
+
+// sortCounts returns the counts with the most common blocks first.
+func sortCounts(counts map[string]int) []blockCount {
+	sorted := make([]blockCount, 0, len(counts))
+	for b, n := range counts {
+		sorted = append(sorted, blockCount{b, n})
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+		return sorted[i].Block < sorted[j].Block
+	})
+
+	return sorted
+}
+
+func writeCounts(out io.Writer, counts []blockCount, format string) error {
+	switch format {
+	case "table":
+		tw := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "BLOCK\tCOUNT")
+		for _, c := range counts {
+			fmt.Fprintf(tw, "%s\t%d\n", c.Block, c.Count)
+		}
+		return tw.Flush()
+	case "csv":
+		cw := csv.NewWriter(out)
+		_ = cw.Write([]string{"block", "count"})
+		for _, c := range counts {
+			_ = cw.Write([]string{c.Block, strconv.Itoa(c.Count)})
+		}
+		cw.Flush()
+		return cw.Error()
+	case "json":
+		e := json.NewEncoder(out)
+		e.SetIndent("", "  ")
+		return e.Encode(counts)
+	default:
+		return fmt.Errorf("unknown format '%s': expected table, csv or json", format)
+	}
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func newDumpCmd() *cobra.Command {
+	var raw bool
+
+	c := &cobra.Command{
+		Use:   "dump <key>",
+		Short: "Print the value stored with a key",
+		Long: `Print the value stored with a key as a hex dump. The key is given as hex, as printed by
+'keys --hex', or as a name such as '~local_player'.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			key, err := hex.DecodeString(args[0])
+			if err != nil {
+				key = []byte(args[0])
+			}
+
+			w := openWorld()
+			defer closeWorld(w)
+
+			value, err := w.Value(key)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if raw {
+				if _, err := os.Stdout.Write(value); err != nil {
+					log.Fatal(err)
+				}
+				return
+			}
+
+			fmt.Print(hex.Dump(value))
+		},
+	}
+
+	c.Flags().BoolVar(&raw, "raw", false, "write the value to stdout unchanged")
+
+	return c
+}
@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/danhale-git/mine/world"
+	"github.com/spf13/cobra"
+)
+
+func newEntitiesCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "entities",
+		Short: "List, remove or move entities such as mobs and dropped items",
+	}
+
+	c.AddCommand(newEntitiesListCmd())
+	c.AddCommand(newEntitiesRemoveCmd())
+	c.AddCommand(newEntitiesMoveCmd())
+
+	return c
+}
+
+func newEntitiesListCmd() *cobra.Command {
+	var f entityFilter
+	var count bool
+
+	c := &cobra.Command{
+		Use:   "list",
+		Short: "List entities, or count them by type",
+		Long: `List entities with their type, custom name, position and unique ID, or count them by type with
+--count. Both legacy and actor storage are read.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			w := openWorld()
+			defer closeWorld(w)
+
+			entities, err := f.entities(w)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+
+			if count {
+				counts := make(map[string]int)
+				for _, e := range entities {
+					counts[e.Identifier]++
+				}
+
+				fmt.Fprintln(tw, "TYPE\tCOUNT")
+				for _, c := range sortCounts(counts) {
+					fmt.Fprintf(tw, "%s\t%d\n", c.Block, c.Count)
+				}
+			} else {
+				fmt.Fprintln(tw, "TYPE\tNAME\tX\tY\tZ\tUNIQUE ID")
+				for _, e := range entities {
+					fmt.Fprintf(tw, "%s\t%s\t%.1f\t%.1f\t%.1f\t%d\n",
+						e.Identifier, e.CustomName, e.Pos[0], e.Pos[1], e.Pos[2], e.UniqueID)
+				}
+			}
+
+			if err := tw.Flush(); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	f.addFlags(c)
+	c.Flags().BoolVar(&count, "count", false, "count entities by type")
+
+	return c
+}
+
+func newEntitiesRemoveCmd() *cobra.Command {
+	var f entityFilter
+	var all bool
+	var dryRun bool
+
+	c := &cobra.Command{
+		Use:   "remove",
+		Short: "Remove entities",
+		Long: `Remove the entities which match the flags, e.g. dropped items with '--type minecraft:item'. At
+least one of --type, --box or --name must be given, or --all to remove every entity in the
+dimension.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if f.empty() && !all {
+				log.Fatal("no entities selected: use --type, --box or --name, or --all")
+			}
+
+			w := openWorld()
+			defer closeWorld(w)
+
+			entities, err := f.entities(w)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if !dryRun {
+				for _, e := range entities {
+					if err := w.RemoveEntity(e); err != nil {
+						log.Fatal(err)
+					}
+				}
+			}
+
+			fmt.Printf("removed %d entities\n", len(entities))
+		},
+	}
+
+	f.addFlags(c)
+	c.Flags().BoolVar(&all, "all", false, "remove every entity in the dimension")
+	c.Flags().BoolVar(&dryRun, "dry-run", false, "count the entities which would be removed without removing them")
+
+	return c
+}
+
+func newEntitiesMoveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "move <unique-id> <x,y,z>",
+		Short: "Move an entity to the given position",
+		Long: `Move the entity with the given unique ID, as printed by 'entities list', to the given position in
+the same dimension. Positions may have fractions, e.g. '10.5,64,-3.5'.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			id, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				log.Fatalf("invalid unique id '%s': %s", args[0], err)
+			}
+
+			pos, err := parseEntityPosition(args[1])
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			w := openWorld()
+			defer closeWorld(w)
+
+			entities, err := w.Entities(dimension, nil)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			for _, e := range entities {
+				if e.UniqueID != id {
+					continue
+				}
+
+				if _, err := w.MoveEntity(e, pos); err != nil {
+					log.Fatal(err)
+				}
+
+				return
+			}
+
+			log.Fatalf("no entity with unique id %d in dimension %d", id, dimension)
+		},
+	}
+}
+
+// parseEntityPosition parses a position given as 'x,y,z', where each value may have a fraction.
+func parseEntityPosition(s string) ([3]float32, error) {
+	var pos [3]float32
+
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return pos, fmt.Errorf("invalid position '%s': expected x,y,z", s)
+	}
+
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 32)
+		if err != nil {
+			return pos, fmt.Errorf("invalid position '%s': %w", s, err)
+		}
+		pos[i] = float32(v)
+	}
+
+	return pos, nil
+}
+
+// entityFilter selects entities by the flags shared by entity commands.
+type entityFilter struct {
+	box        string
+	types      []string
+	customName string
+}
+
+func (f *entityFilter) addFlags(c *cobra.Command) {
+	c.Flags().StringVar(&f.box, "box", "", "only entities in the box 'x1,y1,z1,x2,y2,z2'")
+	c.Flags().StringArrayVar(&f.types, "type", nil, "only entities of the type, e.g. 'minecraft:item', may be repeated")
+	c.Flags().StringVar(&f.customName, "name", "", "only entities with the custom name, ignoring case")
+}
+
+// empty reports whether no filter flags are set, so every entity matches.
+func (f *entityFilter) empty() bool {
+	return f.box == "" && len(f.types) == 0 && f.customName == ""
+}
+
+// entities returns the entities in the current dimension which match the filter.
+func (f *entityFilter) entities(w *world.World) ([]world.Entity, error) {
+	var box *world.Box
+
+	if f.box != "" {
+		min, max, err := parseBox(f.box)
+		if err != nil {
+			return nil, err
+		}
+
+		b := world.NewBox(min, max)
+		box = &b
+	}
+
+	types := make(map[string]bool)
+	for _, t := range f.types {
+		types[blockID(t)] = true
+	}
+
+	all, err := w.Entities(dimension, box)
+	if err != nil {
+		return nil, err
+	}
+
+	entities := make([]world.Entity, 0, len(all))
+
+	for _, e := range all {
+		if len(types) > 0 && !types[e.Identifier] {
+			continue
+		}
+
+		if f.customName != "" && !strings.EqualFold(e.CustomName, f.customName) {
+			continue
+		}
+
+		entities = append(entities, e)
+	}
+
+	return entities, nil
+}
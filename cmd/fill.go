@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/danhale-git/mine/world"
+	"github.com/spf13/cobra"
+)
+
+func newFillCmd() *cobra.Command {
+	var waterLogged bool
+
+	c := &cobra.Command{
+		Use:   "fill <x1,y1,z1,x2,y2,z2> <block>",
+		Short: "Set every block in a box to the given block",
+		Long: `Set every block in the box to the given block, given as 'id' or 'id[name=value,...]'.
+
+Sub chunks which are not saved are created if their chunk is saved, otherwise their blocks are
+skipped.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			min, max, err := parseBox(args[0])
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			id, states, err := parseBlock(args[1])
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			w := openWorld()
+			defer closeWorld(w)
+
+			b := world.Block{ID: id, States: typedStates(states), WaterLogged: waterLogged}
+
+			n, err := w.Fill(min, max, dimension, b)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			fmt.Printf("set %d blocks\n", n)
+		},
+	}
+
+	c.Flags().BoolVar(&waterLogged, "waterlogged", false, "fill with water logged blocks")
+
+	return c
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/danhale-git/mine/world"
+	"github.com/spf13/cobra"
+)
+
+func newFindCmd() *cobra.Command {
+	var box string
+	var states []string
+
+	c := &cobra.Command{
+		Use:   "find <block-id>",
+		Short: "Print the coordinates of every block with the given id",
+		Long: `Print the coordinates of every block with the given id and states. Sub chunks whose palette
+does not contain a matching block are skipped.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			id := blockID(args[0])
+
+			want, err := parseStates(states)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			config := world.ScanConfig{}
+
+			if box != "" {
+				min, max, err := parseBox(box)
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				b := world.NewBox(min, max)
+				config.Bounds = &b
+			}
+
+			w := openWorld()
+			defer closeWorld(w)
+
+			mu := sync.Mutex{}
+			found := make([]world.Position, 0)
+
+			match := func(b world.Block) bool {
+				return b.ID == id && stateMatches(b, want)
+			}
+
+			err = w.FindBlocks(context.Background(), dimension, config, match, func(b world.Block) error {
+				mu.Lock()
+				defer mu.Unlock()
+
+				found = append(found, world.Position{X: b.X, Y: b.Y, Z: b.Z})
+				return nil
+			})
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			sort.Slice(found, func(i, j int) bool {
+				a, b := found[i], found[j]
+				if a.X != b.X {
+					return a.X < b.X
+				}
+				if a.Y != b.Y {
+					return a.Y < b.Y
+				}
+				return a.Z < b.Z
+			})
+
+			for _, p := range found {
+				fmt.Println(p.X, p.Y, p.Z)
+			}
+		},
+	}
+
+	c.Flags().StringVar(&box, "box", "", "only search the box 'x1,y1,z1,x2,y2,z2'")
+	c.Flags().StringArrayVar(&states, "state", nil, "only match blocks with the state 'name=value', may be repeated")
+
+	return c
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/danhale-git/mine/world"
+	"github.com/spf13/cobra"
+)
+
+func newGetCmd() *cobra.Command {
+	var asJSON bool
+
+	c := &cobra.Command{
+		Use:   "get <x> <y> <z>",
+		Short: "Print the block at the given coordinates",
+		Args:  cobra.ExactArgs(3),
+		Run: func(cmd *cobra.Command, args []string) {
+			w := openWorld()
+			defer closeWorld(w)
+
+			x, y, z := atoi(args[0]), atoi(args[1]), atoi(args[2])
+
+			b, err := w.GetBlock(x, y, z, dimension)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			var corrupt *world.CorruptBlockEntitiesError
+			if b.Entity, err = w.GetBlockEntity(x, y, z, dimension); errors.As(err, &corrupt) {
+				log.Printf("ignoring block entities: %s", err)
+			} else if err != nil {
+				log.Fatal(err)
+			}
+
+			if asJSON {
+				out, err := json.MarshalIndent(b, "", "  ")
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				fmt.Println(string(out))
+				return
+			}
+
+			fmt.Println(blockString(b, true))
+			if b.Liquid != nil {
+				fmt.Println(blockString(*b.Liquid, true))
+			}
+			if b.Entity != nil {
+				id := "unknown"
+				if t, ok := b.Entity.Child("id"); ok {
+					id = fmt.Sprint(t.Value)
+				}
+				fmt.Printf("block entity %s, use --json to print its data\n", id)
+			}
+		},
+	}
+
+	c.Flags().BoolVar(&asJSON, "json", false, "print the block as json")
+
+	return c
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/danhale-git/mine/leveldb"
+	"github.com/spf13/cobra"
+)
+
+func newKeysCmd() *cobra.Command {
+	var raw bool
+
+	c := &cobra.Command{
+		Use:   "keys",
+		Short: "List the keys in the world database",
+		Long: `List the keys in the world database in key order. Keys are described by their type, coordinates
+and tag. Keys which cannot be parsed are printed as hex.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			w := openWorld()
+			defer closeWorld(w)
+
+			err := w.IterateKeys(nil, func(b []byte) error {
+				k, err := leveldb.ParseKey(b)
+				if raw || err != nil {
+					fmt.Printf("%x\n", b)
+					return nil
+				}
+
+				fmt.Println(k)
+				return nil
+			})
+			if err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	c.Flags().BoolVar(&raw, "hex", false, "print every key as hex")
+
+	return c
+}
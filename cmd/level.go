@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/danhale-git/mine/world"
+	"github.com/spf13/cobra"
+)
+
+func newLevelCmd() *cobra.Command {
+	var spawn string
+	var difficulty string
+	var time int64
+
+	c := &cobra.Command{
+		Use:   "level",
+		Short: "Print or change the world settings in level.dat",
+		Long: `Print the world settings in level.dat, or change them with the flags. The previous level.dat is
+kept as level.dat_old.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			dir := worldDir()
+
+			l, err := world.ReadLevelData(dir)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			changed := false
+
+			if spawn != "" {
+				p, err := parsePosition(spawn)
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				if err := l.SetSpawnPoint(p); err != nil {
+					log.Fatal(err)
+				}
+				changed = true
+			}
+
+			if difficulty != "" {
+				d, err := parseDifficulty(difficulty)
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				if err := l.SetDifficulty(d); err != nil {
+					log.Fatal(err)
+				}
+				changed = true
+			}
+
+			if cmd.Flags().Changed("time") {
+				if err := l.SetTime(time); err != nil {
+					log.Fatal(err)
+				}
+				changed = true
+			}
+
+			if changed {
+				if err := l.Write(dir); err != nil {
+					log.Fatal(err)
+				}
+			}
+
+			p := l.SpawnPoint()
+
+			tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+			fmt.Fprintf(tw, "name\t%s\n", l.LevelName())
+			fmt.Fprintf(tw, "game mode\t%s\n", l.GameMode())
+			fmt.Fprintf(tw, "difficulty\t%s\n", l.Difficulty())
+			fmt.Fprintf(tw, "seed\t%d\n", l.Seed())
+			fmt.Fprintf(tw, "spawn\t%d %d %d\n", p.X, p.Y, p.Z)
+			fmt.Fprintf(tw, "time\t%d\n", l.Time())
+			fmt.Fprintf(tw, "last played\t%s\n", l.LastPlayed().Format("2006-01-02 15:04"))
+
+			experiments := l.Experiments()
+			names := make([]string, 0, len(experiments))
+			for n := range experiments {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+
+			for _, n := range names {
+				fmt.Fprintf(tw, "experiment %s\t%t\n", n, experiments[n])
+			}
+
+			if err := tw.Flush(); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	c.Flags().StringVar(&spawn, "spawn", "", "set the world spawn point 'x,y,z'")
+	c.Flags().StringVar(&difficulty, "difficulty", "", "set the difficulty: peaceful, easy, normal or hard")
+	c.Flags().Int64Var(&time, "time", 0, "set the world time in ticks")
+
+	return c
+}
+
+func newGameRuleCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "gamerule [name [value]]",
+		Short: "Print or change game rules",
+		Long: `Print every game rule in level.dat, print the named game rule, or set it to the given value.
+Boolean rules take true or false and number rules take whole numbers. The previous level.dat is kept as
+level.dat_old.`,
+		Args: cobra.MaximumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			dir := worldDir()
+
+			l, err := world.ReadLevelData(dir)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			switch len(args) {
+			case 0:
+				rules := l.GameRules()
+				names := make([]string, 0, len(rules))
+				for n := range rules {
+					names = append(names, n)
+				}
+				sort.Strings(names)
+
+				for _, n := range names {
+					fmt.Println(n, rules[n])
+				}
+			case 1:
+				v, ok := l.GameRule(args[0])
+				if !ok {
+					log.Fatal(gameRuleError(args[0]))
+				}
+
+				fmt.Println(v)
+			case 2:
+				old, ok := l.GameRule(args[0])
+				if !ok {
+					log.Fatal(gameRuleError(args[0]))
+				}
+
+				var v interface{}
+				if _, ok := old.(bool); ok {
+					v, err = strconv.ParseBool(args[1])
+				} else {
+					v, err = strconv.Atoi(args[1])
+				}
+				if err != nil {
+					log.Fatalf("invalid value for game rule '%s': %s", args[0], err)
+				}
+
+				if err := l.SetGameRule(args[0], v); err != nil {
+					log.Fatal(err)
+				}
+
+				if err := l.Write(dir); err != nil {
+					log.Fatal(err)
+				}
+			}
+		},
+	}
+}
+
+// gameRuleError returns the error for a game rule which is not in level.dat.
+func gameRuleError(name string) error {
+	if world.KnownGameRule(name) {
+		return fmt.Errorf("game rule '%s' is not set in level.dat", name)
+	}
+
+	return fmt.Errorf("unknown game rule '%s'", name)
+}
+
+// parseDifficulty parses a difficulty given by name.
+func parseDifficulty(s string) (world.Difficulty, error) {
+	for d := world.Peaceful; d <= world.Hard; d++ {
+		if d.String() == s {
+			return d, nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid difficulty '%s': expected peaceful, easy, normal or hard", s)
+}
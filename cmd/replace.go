@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/danhale-git/mine/world"
+	"github.com/spf13/cobra"
+)
+
+func newReplaceCmd() *cobra.Command {
+	var box string
+
+	c := &cobra.Command{
+		Use:   "replace <from> <to>",
+		Short: "Replace every block matching one block with another",
+		Long: `Replace every block matching <from> with <to>. Blocks are given as 'id' or 'id[name=value,...]'.
+<from> matches any block with the id and given states. <to> replaces the whole block state.
+
+Sub chunks entirely inside the box, or every sub chunk if no box is given, are changed by rewriting
+their palette. Water logging is kept.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			fromID, fromStates, err := parseBlock(args[0])
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			toID, toStates, err := parseBlock(args[1])
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			config := world.ScanConfig{}
+
+			if box != "" {
+				min, max, err := parseBox(box)
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				b := world.NewBox(min, max)
+				config.Bounds = &b
+			}
+
+			w := openWorld()
+			defer closeWorld(w)
+
+			match := func(b world.Block) bool {
+				return b.ID == fromID && stateMatches(b, fromStates)
+			}
+
+			to := world.Block{ID: toID, States: typedStates(toStates)}
+
+			n, err := w.ReplaceBlocks(context.Background(), dimension, config, match, to)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			fmt.Printf("replaced %d blocks\n", n)
+		},
+	}
+
+	c.Flags().StringVar(&box, "box", "", "only replace blocks in the box 'x1,y1,z1,x2,y2,z2'")
+
+	return c
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/danhale-git/mine/world"
+	"github.com/spf13/cobra"
+)
+
+func newWorldsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "worlds",
+		Short: "List the worlds in the minecraftWorlds directory",
+		Long: `List the worlds in the minecraftWorlds directory, most recently played first. Either the name
+or the directory of a world may be given to --world.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if worldsDir == "" {
+				log.Fatalf("no minecraftWorlds directory given: use --worlds-dir or set %s", worldsDirEnv)
+			}
+
+			worlds, err := world.ListWorlds(worldsDir)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+			fmt.Fprintln(tw, "NAME\tDIRECTORY\tMODE\tLAST PLAYED\tSIZE")
+
+			for _, w := range worlds {
+				size := "?"
+				if n, err := world.WorldSize(w.Path); err == nil {
+					size = byteSize(n)
+				} else {
+					log.Printf("world '%s': %s", w.Dir, err)
+				}
+
+				if w.Err != nil {
+					fmt.Fprintf(tw, "%s\t%s\t?\t?\t%s\n", w.Name, w.Dir, size)
+					continue
+				}
+
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+					w.Name, w.Dir, w.GameMode, w.LastPlayed.Format("2006-01-02 15:04"), size)
+			}
+
+			if err := tw.Flush(); err != nil {
+				log.Fatal(err)
+			}
+
+			for _, w := range worlds {
+				if w.Err != nil {
+					log.Printf("world '%s': %s", w.Dir, w.Err)
+				}
+			}
+		},
+	}
+}
+
+// byteSize formats a number of bytes with a binary unit, e.g. '1.5 MiB'.
+func byteSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -71,5 +71,5 @@ Write-Host mcpetool db get $key
 #
 #"Overworld block entity data key"
 ## Y is irrelevant here and Dimension redundant, but I can't use named parameters from PowerShell to C#
-#[McBedrockTool]::GetKeyByCoords(413,54,90, 0, 50)
-## 190000000300000032
\ No newline at end of file
+#[McBedrockTool]::GetKeyByCoords(413,54,90, 0, 49)
+## 190000000300000031
\ No newline at end of file
@@ -3,7 +3,9 @@ module github.com/samdiefenbacher/leveldbtool
 go 1.16
 
 require (
-	github.com/danhale-git/nbt2json v0.5.0
-	github.com/midnightfreddie/McpeTool v0.3.2
+	github.com/golang/snappy v0.0.1 // indirect
+	github.com/midnightfreddie/goleveldb v0.0.0-20180127105940-fb12d34a9c1f
+	github.com/onsi/ginkgo v1.12.0 // indirect
+	github.com/onsi/gomega v1.10.0 // indirect
 	github.com/spf13/cobra v1.2.1
 )
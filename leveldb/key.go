@@ -0,0 +1,340 @@
+package leveldb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Tag is the record type byte of a chunk key.
+//
+// https://minecraft.fandom.com/wiki/Bedrock_Edition_level_format#Chunk_key_format
+type Tag byte
+
+const (
+	Data3D                             Tag = 43
+	Version                            Tag = 44
+	Data2D                             Tag = 45
+	Data2DLegacy                       Tag = 46
+	SubChunkPrefix                     Tag = 47
+	LegacyTerrain                      Tag = 48
+	BlockEntity                        Tag = 49
+	Entity                             Tag = 50
+	PendingTicks                       Tag = 51
+	LegacyBlockExtraData               Tag = 52
+	BiomeState                         Tag = 53
+	FinalizedState                     Tag = 54
+	ConversionData                     Tag = 55
+	BorderBlocks                       Tag = 56
+	HardcodedSpawners                  Tag = 57
+	RandomTicks                        Tag = 58
+	Checksums                          Tag = 59
+	GenerationSeed                     Tag = 60
+	GeneratedPreCavesAndCliffsBlending Tag = 61
+	BlendingBiomeHeight                Tag = 62
+	MetaDataHash                       Tag = 63
+	BlendingData                       Tag = 64
+	ActorDigestVersion                 Tag = 65
+	LegacyVersion                      Tag = 118
+)
+
+var tagNames = map[Tag]string{
+	Data3D:                             "Data3D",
+	Version:                            "Version",
+	Data2D:                             "Data2D",
+	Data2DLegacy:                       "Data2DLegacy",
+	SubChunkPrefix:                     "SubChunkPrefix",
+	LegacyTerrain:                      "LegacyTerrain",
+	BlockEntity:                        "BlockEntity",
+	Entity:                             "Entity",
+	PendingTicks:                       "PendingTicks",
+	LegacyBlockExtraData:               "LegacyBlockExtraData",
+	BiomeState:                         "BiomeState",
+	FinalizedState:                     "FinalizedState",
+	ConversionData:                     "ConversionData",
+	BorderBlocks:                       "BorderBlocks",
+	HardcodedSpawners:                  "HardcodedSpawners",
+	RandomTicks:                        "RandomTicks",
+	Checksums:                          "Checksums",
+	GenerationSeed:                     "GenerationSeed",
+	GeneratedPreCavesAndCliffsBlending: "GeneratedPreCavesAndCliffsBlending",
+	BlendingBiomeHeight:                "BlendingBiomeHeight",
+	MetaDataHash:                       "MetaDataHash",
+	BlendingData:                       "BlendingData",
+	ActorDigestVersion:                 "ActorDigestVersion",
+	LegacyVersion:                      "LegacyVersion",
+}
+
+func (t Tag) String() string {
+	if n, ok := tagNames[t]; ok {
+		return n
+	}
+
+	return fmt.Sprintf("Tag(%d)", byte(t))
+}
+
+// Known reports whether t is a known chunk record tag.
+func (t Tag) Known() bool {
+	_, ok := tagNames[t]
+	return ok
+}
+
+// KeyType is the kind of record a key refers to.
+type KeyType int
+
+const (
+	// ChunkKey keys are chunk coordinates, an optional dimension and a Tag.
+	ChunkKey KeyType = iota
+	// NameKey keys are plain strings such as '~local_player' or 'portals'.
+	NameKey
+	// ActorKey keys are 'actorprefix' followed by an actor's 8 byte unique ID.
+	ActorKey
+	// DigestKey keys are 'digp' followed by chunk coordinates and an optional dimension. Their value lists the actor
+	// IDs in that chunk.
+	DigestKey
+)
+
+// Names of well known string keys.
+const (
+	LocalPlayer        = "~local_player"
+	Portals            = "portals"
+	Scoreboard         = "scoreboard"
+	AutonomousEntities = "AutonomousEntities"
+	BiomeData          = "BiomeData"
+	MobEvents          = "mobevents"
+	SchedulerWT        = "schedulerWT"
+	Overworld          = "Overworld"
+	Nether             = "Nether"
+	TheEnd             = "TheEnd"
+	LevelChunkMetaData = "LevelChunkMetaDataDictionary"
+)
+
+const (
+	actorPrefix  = "actorprefix"
+	digestPrefix = "digp"
+)
+
+// namePrefixes are the prefixes of string keys with variable suffixes, such as map and player keys. Some of these keys
+// have the length of a chunk key, so they are checked first.
+var namePrefixes = []string{
+	"map_",
+	"player_",
+	"VILLAGE_",
+	"game_",
+	"structuretemplate",
+	"tickingarea_",
+	"PosTrackDB-",
+}
+
+// maxChunkCoord is the largest absolute chunk coordinate, as worlds end 30 million blocks from the origin.
+const maxChunkCoord = 30000000 / 16
+
+var knownNames = map[string]bool{
+	LocalPlayer:        true,
+	Portals:            true,
+	Scoreboard:         true,
+	AutonomousEntities: true,
+	BiomeData:          true,
+	MobEvents:          true,
+	SchedulerWT:        true,
+	Overworld:          true,
+	Nether:             true,
+	TheEnd:             true,
+	LevelChunkMetaData: true,
+}
+
+// Key is a decoded leveldb key. Which fields are used depends on Type.
+type Key struct {
+	Type KeyType
+
+	// X and Z are chunk coordinates, used by ChunkKey and DigestKey keys.
+	X, Z      int32
+	Dimension int32
+
+	// Tag and SubChunkY are used by ChunkKey keys. SubChunkY is only used with the SubChunkPrefix tag.
+	Tag       Tag
+	SubChunkY int8
+
+	// Name is used by NameKey keys.
+	Name string
+
+	// ActorID is used by ActorKey keys. It is stored as raw bytes, as listed in the value of a DigestKey record.
+	ActorID [8]byte
+}
+
+// NewChunkKey returns the key for the record with the given tag in the chunk at the given chunk coordinates.
+func NewChunkKey(x, z, dimension int32, tag Tag) Key {
+	return Key{Type: ChunkKey, X: x, Z: z, Dimension: dimension, Tag: tag}
+}
+
+// NewSubChunkKey returns the key for the sub chunk at the given chunk coordinates and sub chunk Y index.
+func NewSubChunkKey(x, z, dimension int32, y int8) Key {
+	return Key{Type: ChunkKey, X: x, Z: z, Dimension: dimension, Tag: SubChunkPrefix, SubChunkY: y}
+}
+
+// NewNameKey returns a string key such as LocalPlayer or Portals.
+func NewNameKey(name string) Key {
+	return Key{Type: NameKey, Name: name}
+}
+
+// NewActorKey returns the key for the actor with the given unique ID.
+func NewActorKey(id [8]byte) Key {
+	return Key{Type: ActorKey, ActorID: id}
+}
+
+// NewDigestKey returns the key for the actor digest of the chunk at the given chunk coordinates.
+func NewDigestKey(x, z, dimension int32) Key {
+	return Key{Type: DigestKey, X: x, Z: z, Dimension: dimension}
+}
+
+// Bytes encodes the key as it is stored in the database.
+func (k Key) Bytes() []byte {
+	key := make([]byte, 0)
+
+	switch k.Type {
+	case NameKey:
+		key = append(key, []byte(k.Name)...)
+	case ActorKey:
+		key = append(key, []byte(actorPrefix)...)
+		key = append(key, k.ActorID[:]...)
+	case DigestKey:
+		key = append(key, []byte(digestPrefix)...)
+		key = append(key, k.chunkBytes()...)
+	default:
+		key = append(key, k.chunkBytes()...)
+		key = append(key, byte(k.Tag))
+		if k.Tag == SubChunkPrefix {
+			key = append(key, byte(k.SubChunkY))
+		}
+	}
+
+	return key
+}
+
+// chunkBytes returns the chunk coordinates, followed by the dimension if it is not the overworld.
+func (k Key) chunkBytes() []byte {
+	key := make([]byte, 0)
+
+	key = append(key, littleEndianBytes(k.X)...)
+	key = append(key, littleEndianBytes(k.Z)...)
+
+	if k.Dimension != 0 {
+		key = append(key, littleEndianBytes(k.Dimension)...)
+	}
+
+	return key
+}
+
+func (k Key) String() string {
+	switch k.Type {
+	case NameKey:
+		return k.Name
+	case ActorKey:
+		return fmt.Sprintf("%s %x", actorPrefix, k.ActorID)
+	case DigestKey:
+		return fmt.Sprintf("%s %d %d dimension %d", digestPrefix, k.X, k.Z, k.Dimension)
+	default:
+		if k.Tag == SubChunkPrefix {
+			return fmt.Sprintf("%s %d %d %d dimension %d", k.Tag, k.X, k.SubChunkY, k.Z, k.Dimension)
+		}
+		return fmt.Sprintf("%s %d %d dimension %d", k.Tag, k.X, k.Z, k.Dimension)
+	}
+}
+
+// ParseKey decodes a key read from the database.
+func ParseKey(b []byte) (Key, error) {
+	s := string(b)
+
+	if knownNames[s] {
+		return NewNameKey(s), nil
+	}
+
+	for _, p := range namePrefixes {
+		if strings.HasPrefix(s, p) {
+			return NewNameKey(s), nil
+		}
+	}
+
+	if bytes.HasPrefix(b, []byte(actorPrefix)) && len(b) == len(actorPrefix)+8 {
+		var id [8]byte
+		copy(id[:], b[len(actorPrefix):])
+		return NewActorKey(id), nil
+	}
+
+	if bytes.HasPrefix(b, []byte(digestPrefix)) {
+		switch len(b) - len(digestPrefix) {
+		case 8:
+			return NewDigestKey(readInt32(b[4:]), readInt32(b[8:]), 0), nil
+		case 12:
+			return NewDigestKey(readInt32(b[4:]), readInt32(b[8:]), readInt32(b[12:])), nil
+		}
+	}
+
+	if k, ok := parseChunkKey(b); ok {
+		return k, nil
+	}
+
+	if isPrintable(b) {
+		return NewNameKey(s), nil
+	}
+
+	return Key{}, fmt.Errorf("unrecognised key '%x'", b)
+}
+
+// parseChunkKey decodes b as a chunk key, returning false if it does not have the length and tag of a chunk key or its
+// coordinates are outside the world.
+func parseChunkKey(b []byte) (Key, bool) {
+	k := Key{Type: ChunkKey}
+
+	switch len(b) {
+	case 9, 10:
+		k.Tag = Tag(b[8])
+	case 13, 14:
+		k.Tag = Tag(b[12])
+		k.Dimension = readInt32(b[8:])
+	default:
+		return Key{}, false
+	}
+
+	if !k.Tag.Known() {
+		return Key{}, false
+	}
+
+	// Only sub chunk keys have the trailing Y index
+	hasY := len(b) == 10 || len(b) == 14
+	if hasY != (k.Tag == SubChunkPrefix) {
+		return Key{}, false
+	}
+
+	k.X = readInt32(b)
+	k.Z = readInt32(b[4:])
+
+	if k.X < -maxChunkCoord || k.X > maxChunkCoord || k.Z < -maxChunkCoord || k.Z > maxChunkCoord {
+		return Key{}, false
+	}
+
+	if hasY {
+		k.SubChunkY = int8(b[len(b)-1])
+	}
+
+	return k, true
+}
+
+func isPrintable(b []byte) bool {
+	if len(b) == 0 {
+		return false
+	}
+
+	for _, c := range b {
+		if c < 0x20 || c > 0x7E {
+			return false
+		}
+	}
+
+	return true
+}
+
+func readInt32(b []byte) int32 {
+	return int32(binary.LittleEndian.Uint32(b))
+}
@@ -0,0 +1,63 @@
+package leveldb
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestParseKey(t *testing.T) {
+	keys := []Key{
+		NewSubChunkKey(1, -1, 0, -4),
+		NewSubChunkKey(-26, 3, 1, 5),
+		NewChunkKey(25, 3, 0, BlockEntity),
+		NewChunkKey(-26, 3, -1, Entity),
+		NewChunkKey(0, 0, 2, Data3D),
+		NewChunkKey(0, 0, 0, Version),
+		NewNameKey(LocalPlayer),
+		NewNameKey(Portals),
+		NewNameKey("player_server_7a5a6d9e"),
+		NewNameKey("map_-1234"),
+		NewNameKey("map_12345"),
+		NewNameKey("player_1234"),
+		NewNameKey("VILLAGE_abcd"),
+		NewNameKey("tickingarea_1"),
+		NewActorKey([8]byte{0, 0, 0, 1, 0, 0, 0, 2}),
+		NewDigestKey(4, -5, 0),
+		NewDigestKey(4, -5, 1),
+	}
+
+	for _, want := range keys {
+		got, err := ParseKey(want.Bytes())
+		if err != nil {
+			t.Errorf("unexpected error parsing key %s: %s", want, err)
+			continue
+		}
+
+		if got != want {
+			t.Errorf("unexpected key: expected %+v: got %+v", want, got)
+		}
+	}
+}
+
+func TestKeyBytes(t *testing.T) {
+	// Examples from getdata.ps1
+	testKeyBytes(NewSubChunkKey(25, 3, 0, 5), "19000000030000002F05", t)
+	testKeyBytes(NewSubChunkKey(-26, 3, -1, 5), "E6FFFFFF03000000FFFFFFFF2F05", t)
+	testKeyBytes(NewSubChunkKey(25, -4, 1, 5), "19000000FCFFFFFF010000002F05", t)
+	testKeyBytes(NewChunkKey(25, 3, 0, BlockEntity), "190000000300000031", t)
+}
+
+func testKeyBytes(k Key, want string, t *testing.T) {
+	got := strings.ToUpper(hex.EncodeToString(k.Bytes()))
+
+	if want != got {
+		t.Errorf("unexpected key bytes for %s '%s': expected '%s'", k, got, want)
+	}
+}
+
+func TestParseKeyInvalid(t *testing.T) {
+	if _, err := ParseKey([]byte{0, 1, 2}); err == nil {
+		t.Errorf("expected error parsing unrecognised key")
+	}
+}
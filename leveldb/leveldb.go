@@ -2,6 +2,8 @@ package leveldb
 
 import (
 	"encoding/binary"
+	"errors"
+	"fmt"
 	"math"
 )
 
@@ -9,7 +11,11 @@ const (
 	chunkSize = 16
 )
 
-// SubChunkKey builds the levelDB key for the sub chunk at the given x/y/z coordinates.
+// ErrNotFound is returned by a database when there is no value with the requested key.
+var ErrNotFound = errors.New("leveldb: not found")
+
+// SubChunkKey builds the levelDB key for the sub chunk at the given x/y/z coordinates. The sub chunk Y index is stored
+// as a signed byte, so sub chunks below y=0 in 1.18+ worlds have indices -4 to -1.
 //
 // https://minecraft.fandom.com/wiki/Bedrock_Edition_level_format#NBT_Structure
 func SubChunkKey(x, y, z, dimension int) ([]byte, error) {
@@ -17,19 +23,11 @@ func SubChunkKey(x, y, z, dimension int) ([]byte, error) {
 	zi := int32(math.Floor(float64(z) / chunkSize))
 	yi := int(math.Floor(float64(y) / chunkSize))
 
-	key := make([]byte, 0)
-
-	key = append(key, littleEndianBytes(xi)...)
-	key = append(key, littleEndianBytes(zi)...)
-
-	if dimension != 0 {
-		key = append(key, littleEndianBytes(int32(dimension))...)
+	if yi < math.MinInt8 || yi > math.MaxInt8 {
+		return nil, fmt.Errorf("y coordinate %d is out of range: sub chunk index %d must fit in a signed byte", y, yi)
 	}
 
-	key = append(key, []byte{47}...) // 47 is the SubChunkPrefix key type tag
-	key = append(key, byte(yi))
-
-	return key, nil
+	return NewSubChunkKey(xi, zi, int32(dimension), int8(yi)).Bytes(), nil
 }
 
 func littleEndianBytes(i int32) []byte {
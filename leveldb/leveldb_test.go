@@ -10,6 +10,14 @@ func TestSubChunkKey(t *testing.T) {
 	testSubChunkKey(0, 0, 0, "00000000000000002F00", t)
 	testSubChunkKey(16, 16, 16, "01000000010000002F01", t)
 	testSubChunkKey(-1, 32, -1, "FFFFFFFFFFFFFFFF2F02", t)
+	testSubChunkKey(0, -1, 0, "00000000000000002FFF", t)
+	testSubChunkKey(0, -64, 0, "00000000000000002FFC", t)
+}
+
+func TestSubChunkKeyOutOfRange(t *testing.T) {
+	if _, err := SubChunkKey(0, 128*16, 0, 0); err == nil {
+		t.Errorf("expected error for y index out of range")
+	}
 }
 
 func testSubChunkKey(x, y, z int, want string, t *testing.T) {
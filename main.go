@@ -0,0 +1,13 @@
+package main
+
+import (
+	"os"
+
+	"github.com/danhale-git/mine/cmd"
+)
+
+func main() {
+	if err := cmd.Init(); err != nil {
+		os.Exit(1)
+	}
+}
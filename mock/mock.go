@@ -36,6 +36,12 @@ func SubChunkReader() *bytes.Reader {
 	return bytes.NewReader(SubChunkValue)
 }
 
+// SubChunkValueVersion9 returns SubChunkValue in the version 9 format, with the given sub chunk y index.
+func SubChunkValueVersion9(y int8) []byte {
+	data := []byte{9, SubChunkValue[1], byte(y)}
+	return append(data, SubChunkValue[2:]...)
+}
+
 func ByteSliceAsString(data []byte) string {
 	byteStrings := make([]string, len(data))
 
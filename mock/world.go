@@ -1,13 +1,128 @@
 package mock
 
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/danhale-git/mine/leveldb"
+	"github.com/danhale-git/mine/nbt"
+)
+
+// LevelDB is an in memory database. Sub chunk keys which have not been put return the default data, if it is set. It is
+// safe for concurrent use.
 type LevelDB struct {
-	data []byte
+	mu      sync.RWMutex
+	data    []byte
+	records map[string][]byte
 }
 
-func (w *LevelDB) Get(_ []byte) ([]byte, error) {
+func (w *LevelDB) Get(key []byte) ([]byte, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if v, ok := w.records[string(key)]; ok {
+		return v, nil
+	}
+
+	if k, err := leveldb.ParseKey(key); w.data == nil || err != nil || k.Tag != leveldb.SubChunkPrefix {
+		return nil, leveldb.ErrNotFound
+	}
+
 	return w.data, nil
 }
 
+func (w *LevelDB) Put(key, value []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.records[string(key)] = value
+	return nil
+}
+
+func (w *LevelDB) Delete(key []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.records, string(key))
+	return nil
+}
+
+// Close does nothing, as there is nothing to release.
+func (w *LevelDB) Close() error {
+	return nil
+}
+
+// Iterate calls fn with each key which has been put and starts with prefix, in key order. Keys served by the default
+// data are not included. The keys are listed when Iterate is called, so fn may change the database.
+func (w *LevelDB) Iterate(prefix []byte, fn func(key []byte) error) error {
+	w.mu.RLock()
+
+	keys := make([]string, 0, len(w.records))
+	for k := range w.records {
+		if strings.HasPrefix(k, string(prefix)) {
+			keys = append(keys, k)
+		}
+	}
+
+	w.mu.RUnlock()
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if err := fn([]byte(k)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidLevelDB returns a database which returns SubChunkValue for every sub chunk key.
 func ValidLevelDB() *LevelDB {
-	return &LevelDB{SubChunkValue}
+	return &LevelDB{data: SubChunkValue, records: make(map[string][]byte)}
+}
+
+// LevelDBWithKeys returns a database which stores SubChunkValue at each of the given keys and has no default data.
+func LevelDBWithKeys(keys ...[]byte) *LevelDB {
+	records := make([]Record, len(keys))
+	for i, k := range keys {
+		records[i] = Record{Key: k, Value: SubChunkValue}
+	}
+
+	return LevelDBWithRecords(records...)
+}
+
+// Record is a value stored by LevelDBWithRecords. If NBT is set the value is the given tags written one after another,
+// as in BlockEntity and Entity records, otherwise it is Value.
+type Record struct {
+	Key   []byte
+	Value []byte
+	NBT   []nbt.NBTTag
+}
+
+// LevelDBWithRecords returns a database which stores each of the given records and has no default data. It panics if a
+// tag can't be encoded.
+func LevelDBWithRecords(records ...Record) *LevelDB {
+	db := &LevelDB{records: make(map[string][]byte)}
+
+	for _, r := range records {
+		value := r.Value
+
+		if r.NBT != nil {
+			buf := bytes.Buffer{}
+			for _, t := range r.NBT {
+				if err := nbt.Write(&buf, t); err != nil {
+					panic(fmt.Sprintf("encoding record with key '%x': %s", r.Key, err))
+				}
+			}
+			value = buf.Bytes()
+		}
+
+		db.records[string(r.Key)] = value
+	}
+
+	return db
 }
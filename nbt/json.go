@@ -0,0 +1,121 @@
+package nbt
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// UnmarshalJSON decodes a tag encoded by encoding/json, converting the value to the Go type for the tag type. Numbers
+// which do not fit the tag type are an error.
+func (n *NBTTag) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Type  byte            `json:"tagType"`
+		Name  string          `json:"name"`
+		Value json.RawMessage `json:"value"`
+	}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	v, err := decodeJSONValue(raw.Type, raw.Value)
+	if err != nil {
+		return fmt.Errorf("decoding value of tag '%s': %w", raw.Name, err)
+	}
+
+	n.Type, n.Name, n.Value = raw.Type, raw.Name, v
+
+	return nil
+}
+
+// UnmarshalJSON decodes a list encoded by encoding/json, converting each value to the Go type for the list type.
+func (l *List) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Type   byte              `json:"tagListType"`
+		Values []json.RawMessage `json:"list"`
+	}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	l.Type = raw.Type
+	l.Values = nil
+
+	if raw.Values == nil {
+		return nil
+	}
+
+	l.Values = make([]interface{}, len(raw.Values))
+
+	for i, r := range raw.Values {
+		v, err := decodeJSONValue(raw.Type, r)
+		if err != nil {
+			return fmt.Errorf("decoding list element %d: %w", i, err)
+		}
+
+		l.Values[i] = v
+	}
+
+	return nil
+}
+
+// decodeJSONValue decodes the value of a tag of the given type.
+func decodeJSONValue(tagType byte, data json.RawMessage) (interface{}, error) {
+	var err error
+
+	switch tagType {
+	case TagEnd:
+		return nil, nil
+	case TagByte:
+		var v int8
+		err = json.Unmarshal(data, &v)
+		return v, err
+	case TagShort:
+		var v int16
+		err = json.Unmarshal(data, &v)
+		return v, err
+	case TagInt:
+		var v int32
+		err = json.Unmarshal(data, &v)
+		return v, err
+	case TagLong:
+		var v int64
+		err = json.Unmarshal(data, &v)
+		return v, err
+	case TagFloat:
+		var v float32
+		err = json.Unmarshal(data, &v)
+		return v, err
+	case TagDouble:
+		var v float64
+		err = json.Unmarshal(data, &v)
+		return v, err
+	case TagByteArray:
+		var v []byte
+		err = json.Unmarshal(data, &v)
+		return v, err
+	case TagString:
+		var v string
+		err = json.Unmarshal(data, &v)
+		return v, err
+	case TagList:
+		var v List
+		err = json.Unmarshal(data, &v)
+		return v, err
+	case TagCompound:
+		v := make([]NBTTag, 0)
+		err = json.Unmarshal(data, &v)
+		return v, err
+	case TagIntArray:
+		var v []int32
+		err = json.Unmarshal(data, &v)
+		return v, err
+	case TagLongArray:
+		var v []int64
+		err = json.Unmarshal(data, &v)
+		return v, err
+	default:
+		return nil, fmt.Errorf("unknown tag type %d", tagType)
+	}
+}
@@ -0,0 +1,393 @@
+package nbt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Tag types as stored in the first byte of a named tag.
+//
+// https://minecraft.fandom.com/wiki/NBT_format#Binary_format
+const (
+	TagEnd byte = iota
+	TagByte
+	TagShort
+	TagInt
+	TagLong
+	TagFloat
+	TagDouble
+	TagByteArray
+	TagString
+	TagList
+	TagCompound
+	TagIntArray
+	TagLongArray
+)
+
+// maxPrealloc limits the number of elements allocated from a length prefix before they are read. Longer values grow as
+// they are read, so a corrupt length fails when the input runs out rather than exhausting memory.
+const maxPrealloc = 4096
+
+// List is the value of a TagList tag. All values have the type given by Type and are stored as they would be in the
+// Value field of an NBTTag of that type.
+type List struct {
+	Type   byte          `json:"tagListType"`
+	Values []interface{} `json:"list"`
+}
+
+// Read reads a single named tag from r. Bedrock Edition NBT is little-endian.
+func Read(r io.Reader) (NBTTag, error) {
+	t := NBTTag{}
+
+	if err := readLittleEndian(r, &t.Type); err != nil {
+		return t, fmt.Errorf("reading tag type: %w", err)
+	}
+
+	if t.Type == TagEnd {
+		return t, nil
+	}
+
+	var err error
+
+	t.Name, err = readString(r)
+	if err != nil {
+		return t, fmt.Errorf("reading tag name: %w", err)
+	}
+
+	t.Value, err = readPayload(r, t.Type)
+	if err != nil {
+		return t, fmt.Errorf("reading value of tag '%s': %w", t.Name, err)
+	}
+
+	return t, nil
+}
+
+// Write writes a single named tag to w. It is the inverse of Read.
+func Write(w io.Writer, t NBTTag) error {
+	if err := writeLittleEndian(w, t.Type); err != nil {
+		return fmt.Errorf("writing tag type: %w", err)
+	}
+
+	if t.Type == TagEnd {
+		return nil
+	}
+
+	if err := writeString(w, t.Name); err != nil {
+		return fmt.Errorf("writing tag name: %w", err)
+	}
+
+	if err := writePayload(w, t.Type, t.Value); err != nil {
+		return fmt.Errorf("writing value of tag '%s': %w", t.Name, err)
+	}
+
+	return nil
+}
+
+func readPayload(r io.Reader, tagType byte) (interface{}, error) {
+	switch tagType {
+	case TagByte:
+		var v int8
+		err := readLittleEndian(r, &v)
+		return v, err
+	case TagShort:
+		var v int16
+		err := readLittleEndian(r, &v)
+		return v, err
+	case TagInt:
+		var v int32
+		err := readLittleEndian(r, &v)
+		return v, err
+	case TagLong:
+		var v int64
+		err := readLittleEndian(r, &v)
+		return v, err
+	case TagFloat:
+		var v float32
+		err := readLittleEndian(r, &v)
+		return v, err
+	case TagDouble:
+		var v float64
+		err := readLittleEndian(r, &v)
+		return v, err
+	case TagByteArray:
+		n, err := readLength(r)
+		if err != nil {
+			return nil, err
+		}
+		return readBytes(r, n)
+	case TagString:
+		return readString(r)
+	case TagList:
+		return readList(r)
+	case TagCompound:
+		return readCompound(r)
+	case TagIntArray:
+		n, err := readLength(r)
+		if err != nil {
+			return nil, err
+		}
+		v := make([]int32, 0, prealloc(n))
+		for len(v) < n {
+			chunk := make([]int32, prealloc(n-len(v)))
+			if err := readLittleEndian(r, chunk); err != nil {
+				return nil, err
+			}
+			v = append(v, chunk...)
+		}
+		return v, nil
+	case TagLongArray:
+		n, err := readLength(r)
+		if err != nil {
+			return nil, err
+		}
+		v := make([]int64, 0, prealloc(n))
+		for len(v) < n {
+			chunk := make([]int64, prealloc(n-len(v)))
+			if err := readLittleEndian(r, chunk); err != nil {
+				return nil, err
+			}
+			v = append(v, chunk...)
+		}
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unknown tag type %d", tagType)
+	}
+}
+
+func writePayload(w io.Writer, tagType byte, value interface{}) error {
+	switch tagType {
+	case TagByte, TagShort, TagInt, TagLong, TagFloat, TagDouble:
+		if err := checkType(tagType, value); err != nil {
+			return err
+		}
+		return writeLittleEndian(w, value)
+	case TagByteArray:
+		v, ok := value.([]byte)
+		if !ok {
+			return typeError(tagType, value)
+		}
+		if err := writeLittleEndian(w, int32(len(v))); err != nil {
+			return err
+		}
+		_, err := w.Write(v)
+		return err
+	case TagString:
+		v, ok := value.(string)
+		if !ok {
+			return typeError(tagType, value)
+		}
+		return writeString(w, v)
+	case TagList:
+		v, ok := value.(List)
+		if !ok {
+			return typeError(tagType, value)
+		}
+		return writeList(w, v)
+	case TagCompound:
+		v, ok := value.([]NBTTag)
+		if !ok {
+			return typeError(tagType, value)
+		}
+		return writeCompound(w, v)
+	case TagIntArray:
+		v, ok := value.([]int32)
+		if !ok {
+			return typeError(tagType, value)
+		}
+		if err := writeLittleEndian(w, int32(len(v))); err != nil {
+			return err
+		}
+		return writeLittleEndian(w, v)
+	case TagLongArray:
+		v, ok := value.([]int64)
+		if !ok {
+			return typeError(tagType, value)
+		}
+		if err := writeLittleEndian(w, int32(len(v))); err != nil {
+			return err
+		}
+		return writeLittleEndian(w, v)
+	default:
+		return fmt.Errorf("unknown tag type %d", tagType)
+	}
+}
+
+func readList(r io.Reader) (List, error) {
+	l := List{}
+
+	if err := readLittleEndian(r, &l.Type); err != nil {
+		return l, fmt.Errorf("reading list type: %w", err)
+	}
+
+	n, err := readLength(r)
+	if err != nil {
+		return l, fmt.Errorf("reading list length: %w", err)
+	}
+
+	// An empty list may have the end tag as its type
+	if n == 0 {
+		return l, nil
+	}
+
+	l.Values = make([]interface{}, 0, prealloc(n))
+
+	for i := 0; i < n; i++ {
+		v, err := readPayload(r, l.Type)
+		if err != nil {
+			return l, fmt.Errorf("reading list element %d: %w", i, err)
+		}
+		l.Values = append(l.Values, v)
+	}
+
+	return l, nil
+}
+
+func writeList(w io.Writer, l List) error {
+	if err := writeLittleEndian(w, l.Type); err != nil {
+		return fmt.Errorf("writing list type: %w", err)
+	}
+
+	if err := writeLittleEndian(w, int32(len(l.Values))); err != nil {
+		return fmt.Errorf("writing list length: %w", err)
+	}
+
+	for i, v := range l.Values {
+		if err := writePayload(w, l.Type, v); err != nil {
+			return fmt.Errorf("writing list element %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func readCompound(r io.Reader) ([]NBTTag, error) {
+	tags := make([]NBTTag, 0)
+
+	for {
+		t, err := Read(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if t.Type == TagEnd {
+			return tags, nil
+		}
+
+		tags = append(tags, t)
+	}
+}
+
+func writeCompound(w io.Writer, tags []NBTTag) error {
+	for _, t := range tags {
+		if err := Write(w, t); err != nil {
+			return err
+		}
+	}
+
+	return writeLittleEndian(w, TagEnd)
+}
+
+func readString(r io.Reader) (string, error) {
+	var n uint16
+	if err := readLittleEndian(r, &n); err != nil {
+		return "", fmt.Errorf("reading string length: %w", err)
+	}
+
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", fmt.Errorf("reading string: %w", err)
+	}
+
+	return string(b), nil
+}
+
+func writeString(w io.Writer, s string) error {
+	if len(s) > 0xFFFF {
+		return fmt.Errorf("string of length %d exceeds maximum length %d", len(s), 0xFFFF)
+	}
+
+	if err := writeLittleEndian(w, uint16(len(s))); err != nil {
+		return fmt.Errorf("writing string length: %w", err)
+	}
+
+	_, err := w.Write([]byte(s))
+
+	return err
+}
+
+// readLength reads the int32 length prefix of a list or array.
+func readLength(r io.Reader) (int, error) {
+	var n int32
+	if err := readLittleEndian(r, &n); err != nil {
+		return 0, err
+	}
+
+	if n < 0 {
+		return 0, fmt.Errorf("invalid negative length %d", n)
+	}
+
+	return int(n), nil
+}
+
+// readBytes reads a byte array of length n, growing it as it is read.
+func readBytes(r io.Reader, n int) ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, prealloc(n)))
+
+	if _, err := io.CopyN(buf, r, int64(n)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// prealloc returns the number of elements to allocate for a value of length n before it is read.
+func prealloc(n int) int {
+	if n > maxPrealloc {
+		return maxPrealloc
+	}
+
+	return n
+}
+
+// checkType returns an error if value is not the Go type used for the given numeric tag type.
+func checkType(tagType byte, value interface{}) error {
+	ok := false
+
+	switch tagType {
+	case TagByte:
+		_, ok = value.(int8)
+	case TagShort:
+		_, ok = value.(int16)
+	case TagInt:
+		_, ok = value.(int32)
+	case TagLong:
+		_, ok = value.(int64)
+	case TagFloat:
+		_, ok = value.(float32)
+	case TagDouble:
+		_, ok = value.(float64)
+	}
+
+	if !ok {
+		return typeError(tagType, value)
+	}
+
+	return nil
+}
+
+func typeError(tagType byte, value interface{}) error {
+	return fmt.Errorf("value of type %T is not valid for tag type %d", value, tagType)
+}
+
+func readLittleEndian(r io.Reader, data interface{}) error {
+	return binary.Read(r, binary.LittleEndian, data)
+}
+
+func writeLittleEndian(w io.Writer, data interface{}) error {
+	return binary.Write(w, binary.LittleEndian, data)
+}
@@ -0,0 +1,149 @@
+package nbt
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// testTag returns a compound containing every tag type.
+func testTag() NBTTag {
+	return NBTTag{
+		Type: TagCompound,
+		Name: "root",
+		Value: []NBTTag{
+			{Type: TagByte, Name: "byte", Value: int8(-1)},
+			{Type: TagShort, Name: "short", Value: int16(300)},
+			{Type: TagInt, Name: "int", Value: int32(-70000)},
+			{Type: TagLong, Name: "long", Value: int64(1) << 40},
+			{Type: TagFloat, Name: "float", Value: float32(1.5)},
+			{Type: TagDouble, Name: "double", Value: 2.25},
+			{Type: TagByteArray, Name: "bytes", Value: []byte{1, 2, 3}},
+			{Type: TagString, Name: "string", Value: "minecraft:stone"},
+			{Type: TagList, Name: "list", Value: List{Type: TagInt, Values: []interface{}{int32(1), int32(2)}}},
+			{Type: TagList, Name: "empty", Value: List{}},
+			{Type: TagList, Name: "compounds", Value: List{Type: TagCompound, Values: []interface{}{
+				[]NBTTag{{Type: TagString, Name: "Name", Value: "minecraft:apple"}},
+			}}},
+			{Type: TagCompound, Name: "compound", Value: []NBTTag{}},
+			{Type: TagIntArray, Name: "ints", Value: []int32{4, 5}},
+			{Type: TagLongArray, Name: "longs", Value: []int64{6, 7}},
+		},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tag := testTag()
+
+	buf := bytes.Buffer{}
+	if err := Write(&buf, tag); err != nil {
+		t.Fatalf("unexpected error writing: %s", err)
+	}
+
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error reading: %s", err)
+	}
+
+	if !reflect.DeepEqual(tag, got) {
+		t.Errorf("tag changed after round trip: expected %+v: got %+v", tag, got)
+	}
+
+	if buf.Len() != 0 {
+		t.Errorf("%d bytes were not read", buf.Len())
+	}
+}
+
+func TestWriteInvalidValue(t *testing.T) {
+	tag := NBTTag{Type: TagInt, Name: "int", Value: 1}
+	if err := Write(&bytes.Buffer{}, tag); err == nil {
+		t.Errorf("expected error writing value of type int as TagInt")
+	}
+}
+
+func TestBlockState(t *testing.T) {
+	states := map[string]interface{}{"stone_type": "granite", "age": 3, "lit": true}
+
+	tag, err := NewBlockState("minecraft:stone", states, 17879555)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if id := tag.BlockID(); id != "minecraft:stone" {
+		t.Errorf("unexpected block id '%s'", id)
+	}
+
+	if v, ok := tag.BlockVersion(); !ok || v != 17879555 {
+		t.Errorf("unexpected block version %d, %t", v, ok)
+	}
+
+	want := map[string]interface{}{"stone_type": "granite", "age": int32(3), "lit": int8(1)}
+	if got := tag.BlockStates(); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected block states: expected %+v: got %+v", want, got)
+	}
+
+	if _, err := NewBlockState("minecraft:stone", map[string]interface{}{"bad": 1.5}, 0); err == nil {
+		t.Errorf("expected error for unsupported state value type")
+	}
+}
+
+func TestSetChild(t *testing.T) {
+	c := NBTTag{Type: TagCompound, Value: []NBTTag{{Type: TagInt, Name: "a", Value: int32(1)}}}
+
+	if err := c.SetChild(NBTTag{Type: TagInt, Name: "a", Value: int32(2)}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := c.SetChild(NBTTag{Type: TagString, Name: "b", Value: "x"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := []NBTTag{{Type: TagInt, Name: "a", Value: int32(2)}, {Type: TagString, Name: "b", Value: "x"}}
+	if !reflect.DeepEqual(c.Value, want) {
+		t.Errorf("expected %+v: got %+v", want, c.Value)
+	}
+
+	notCompound := NBTTag{Type: TagInt, Value: int32(0)}
+	if err := notCompound.SetChild(NBTTag{Type: TagInt, Name: "a"}); err == nil {
+		t.Errorf("expected error setting child of non compound tag")
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	tag := testTag()
+
+	data, err := json.Marshal(tag)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	got := NBTTag{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !reflect.DeepEqual(tag, got) {
+		t.Errorf("tag changed after round trip: expected %+v: got %+v", tag, got)
+	}
+
+	if err := json.Unmarshal([]byte(`{"tagType":1,"name":"b","value":300}`), &got); err == nil {
+		t.Errorf("expected error decoding out of range byte")
+	}
+}
+
+func TestReadHugeLength(t *testing.T) {
+	// Each value is a tag type, an empty name and a payload with the maximum length prefix and no elements
+	values := map[string][]byte{
+		"list":       {TagList, 0, 0, TagInt, 0xFF, 0xFF, 0xFF, 0x7F},
+		"byte array": {TagByteArray, 0, 0, 0xFF, 0xFF, 0xFF, 0x7F, 1, 2},
+		"int array":  {TagIntArray, 0, 0, 0xFF, 0xFF, 0xFF, 0x7F, 1, 2, 3, 4},
+		"long array": {TagLongArray, 0, 0, 0xFF, 0xFF, 0xFF, 0x7F},
+	}
+
+	for name, data := range values {
+		if _, err := Read(bytes.NewReader(data)); err == nil {
+			t.Errorf("expected error reading %s longer than its input", name)
+		}
+	}
+}
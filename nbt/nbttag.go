@@ -1,26 +1,142 @@
 package nbt
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+)
 
+// NBTTag is a single named tag. The type of Value depends on Type: int8, int16, int32, int64, float32, float64, []byte,
+// string, List, []NBTTag (for compounds), []int32 or []int64.
 type NBTTag struct {
 	Type  byte        `json:"tagType"`
 	Name  string      `json:"name"`
 	Value interface{} `json:"value"`
 }
 
+// NewBlockState returns a palette entry for the given block id, states and block version. State values may be bool,
+// int8 or uint8 (stored as bytes), int or int32 (stored as ints) or string.
+func NewBlockState(id string, states map[string]interface{}, version int32) (NBTTag, error) {
+	names := make([]string, 0, len(states))
+	for name := range states {
+		names = append(names, name)
+	}
+
+	// Bedrock stores states in name order
+	sort.Strings(names)
+
+	stateTags := make([]NBTTag, len(names))
+
+	for i, name := range names {
+		t, err := stateTag(name, states[name])
+		if err != nil {
+			return NBTTag{}, err
+		}
+
+		stateTags[i] = t
+	}
+
+	return NBTTag{
+		Type: TagCompound,
+		Value: []NBTTag{
+			{Type: TagString, Name: "name", Value: id},
+			{Type: TagCompound, Name: "states", Value: stateTags},
+			{Type: TagInt, Name: "version", Value: version},
+		},
+	}, nil
+}
+
+func stateTag(name string, value interface{}) (NBTTag, error) {
+	switch v := value.(type) {
+	case bool:
+		var b int8
+		if v {
+			b = 1
+		}
+		return NBTTag{Type: TagByte, Name: name, Value: b}, nil
+	case int8:
+		return NBTTag{Type: TagByte, Name: name, Value: v}, nil
+	case uint8:
+		return NBTTag{Type: TagByte, Name: name, Value: int8(v)}, nil
+	case int:
+		return NBTTag{Type: TagInt, Name: name, Value: int32(v)}, nil
+	case int32:
+		return NBTTag{Type: TagInt, Name: name, Value: v}, nil
+	case string:
+		return NBTTag{Type: TagString, Name: name, Value: v}, nil
+	default:
+		return NBTTag{}, fmt.Errorf("block state '%s' has unsupported value type %T", name, value)
+	}
+}
+
+// Child returns the tag with the given name if this is a compound tag containing it.
+func (n *NBTTag) Child(name string) (*NBTTag, bool) {
+	tags, ok := n.Value.([]NBTTag)
+	if !ok {
+		return nil, false
+	}
+
+	for i := range tags {
+		if tags[i].Name == name {
+			return &tags[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// SetChild replaces the child tag with the same name as t, or adds t if there is none. It returns an error if this is
+// not a compound tag.
+func (n *NBTTag) SetChild(t NBTTag) error {
+	tags, ok := n.Value.([]NBTTag)
+	if !ok {
+		return fmt.Errorf("tag '%s' has type %d: expected compound", n.Name, n.Type)
+	}
+
+	for i := range tags {
+		if tags[i].Name == t.Name {
+			tags[i] = t
+			return nil
+		}
+	}
+
+	n.Value = append(tags, t)
+
+	return nil
+}
+
+// BlockID returns the value of the name tag in a block state, or an empty string if it has no name.
 func (n *NBTTag) BlockID() string {
-	//	fmt.Printf("%+v\n", n)
-	if vs, ok := n.Value.([]interface{}); ok {
-		for _, t := range vs {
-			if tMap, ok := t.(map[string]interface{}); ok {
-				if tMap["name"] == "name" {
-					return tMap["value"].(string)
-				}
-			}
+	if t, ok := n.Child("name"); ok {
+		if id, ok := t.Value.(string); ok {
+			return id
 		}
-	} else {
-		fmt.Println("failed to convert to NBTTag")
 	}
 
 	return ""
 }
+
+// BlockVersion returns the value of the version tag in a block state. The boolean is false if it has no version.
+func (n *NBTTag) BlockVersion() (int32, bool) {
+	if t, ok := n.Child("version"); ok {
+		v, ok := t.Value.(int32)
+		return v, ok
+	}
+
+	return 0, false
+}
+
+// BlockStates returns the values of the states compound in a block state, keyed by state name. Values have the types
+// described by NBTTag. The returned map is empty if the block state has no states.
+func (n *NBTTag) BlockStates() map[string]interface{} {
+	states := make(map[string]interface{})
+
+	if t, ok := n.Child("states"); ok {
+		if tags, ok := t.Value.([]NBTTag); ok {
+			for _, s := range tags {
+				states[s.Name] = s.Value
+			}
+		}
+	}
+
+	return states
+}
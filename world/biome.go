@@ -0,0 +1,342 @@
+package world
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/danhale-git/mine/leveldb"
+)
+
+// Biome is a numeric biome ID as stored in Data2D and Data3D records.
+type Biome int32
+
+// biomeNames are the identifiers of the known biome IDs.
+var biomeNames = map[Biome]string{
+	0:   "ocean",
+	1:   "plains",
+	2:   "desert",
+	3:   "extreme_hills",
+	4:   "forest",
+	5:   "taiga",
+	6:   "swampland",
+	7:   "river",
+	8:   "hell",
+	9:   "the_end",
+	10:  "legacy_frozen_ocean",
+	11:  "frozen_river",
+	12:  "ice_plains",
+	13:  "ice_mountains",
+	14:  "mushroom_island",
+	15:  "mushroom_island_shore",
+	16:  "beach",
+	17:  "desert_hills",
+	18:  "forest_hills",
+	19:  "taiga_hills",
+	20:  "extreme_hills_edge",
+	21:  "jungle",
+	22:  "jungle_hills",
+	23:  "jungle_edge",
+	24:  "deep_ocean",
+	25:  "stone_beach",
+	26:  "cold_beach",
+	27:  "birch_forest",
+	28:  "birch_forest_hills",
+	29:  "roofed_forest",
+	30:  "cold_taiga",
+	31:  "cold_taiga_hills",
+	32:  "mega_taiga",
+	33:  "mega_taiga_hills",
+	34:  "extreme_hills_plus_trees",
+	35:  "savanna",
+	36:  "savanna_plateau",
+	37:  "mesa",
+	38:  "mesa_plateau_stone",
+	39:  "mesa_plateau",
+	40:  "warm_ocean",
+	41:  "deep_warm_ocean",
+	42:  "lukewarm_ocean",
+	43:  "deep_lukewarm_ocean",
+	44:  "cold_ocean",
+	45:  "deep_cold_ocean",
+	46:  "frozen_ocean",
+	47:  "deep_frozen_ocean",
+	48:  "bamboo_jungle",
+	49:  "bamboo_jungle_hills",
+	129: "sunflower_plains",
+	130: "desert_mutated",
+	131: "extreme_hills_mutated",
+	132: "flower_forest",
+	133: "taiga_mutated",
+	134: "swampland_mutated",
+	140: "ice_plains_spikes",
+	149: "jungle_mutated",
+	151: "jungle_edge_mutated",
+	155: "birch_forest_mutated",
+	156: "birch_forest_hills_mutated",
+	157: "roofed_forest_mutated",
+	158: "cold_taiga_mutated",
+	160: "redwood_taiga_mutated",
+	161: "redwood_taiga_hills_mutated",
+	162: "extreme_hills_plus_trees_mutated",
+	163: "savanna_mutated",
+	164: "savanna_plateau_mutated",
+	165: "mesa_bryce",
+	166: "mesa_plateau_stone_mutated",
+	167: "mesa_plateau_mutated",
+	178: "soulsand_valley",
+	179: "crimson_forest",
+	180: "warped_forest",
+	181: "basalt_deltas",
+	182: "jagged_peaks",
+	183: "frozen_peaks",
+	184: "snowy_slopes",
+	185: "grove",
+	186: "meadow",
+	187: "lush_caves",
+	188: "dripstone_caves",
+	189: "stony_peaks",
+	190: "deep_dark",
+	191: "mangrove_swamp",
+	192: "cherry_grove",
+	193: "pale_garden",
+}
+
+// String returns the biome's identifier, e.g. 'plains'.
+func (b Biome) String() string {
+	if name, ok := biomeNames[b]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("unknown (%d)", int32(b))
+}
+
+const (
+	// heightMapSize is the size in bytes of the height map at the start of Data2D and Data3D records.
+	heightMapSize = chunkSize * chunkSize * 2
+
+	// biomeStorageCopyLast is the bits per block and version byte of a Data3D biome storage which is the same as the
+	// storage below it.
+	biomeStorageCopyLast = 0xFF
+)
+
+// biomeData is the parsed value of a chunk's Data3D or Data2D record.
+type biomeData struct {
+	// heights is the height map in world coordinates, indexed by z*16 + x.
+	heights [chunkSize * chunkSize]int
+
+	// biomes2D is set for Data2D records and holds a biome for each column, indexed by z*16 + x.
+	biomes2D []Biome
+
+	// storages is set for Data3D records and holds the biomes of each sub chunk, starting at sub chunk minY.
+	storages []biomeStorage
+	minY     int
+}
+
+// biomeStorage is the biomes of one sub chunk. Indices are ordered like block storage indices and are nil if every
+// biome in the sub chunk is Palette[0].
+type biomeStorage struct {
+	Indices []int
+	Palette []Biome
+}
+
+// GetBiome returns the biome at the given coordinates. Chunks saved before 1.18 have one biome for each column, in
+// which case y is ignored. A *ChunkDataNotSavedError is returned if the chunk has no Data3D or Data2D record.
+func (w *World) GetBiome(x, y, z, dimension int) (Biome, error) {
+	d, err := w.biomeData(x, z, dimension)
+	if err != nil {
+		return 0, err
+	}
+
+	sx, sy, sz := worldVoxelToSubChunk(x, y, z)
+
+	if d.biomes2D != nil {
+		return d.biomes2D[sz*chunkSize+sx], nil
+	}
+
+	i := subChunkOrigin(x, y, z, dimension).y - d.minY
+	if i < 0 || i >= len(d.storages) {
+		return 0, fmt.Errorf("y %d is outside the %d sub chunks of biome data starting at sub chunk %d",
+			y, len(d.storages), d.minY)
+	}
+
+	s := d.storages[i]
+	if s.Indices == nil {
+		return s.Palette[0], nil
+	}
+
+	voxelIndex, err := subChunkVoxelToIndex(sx, sy, sz)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.Palette[s.Indices[voxelIndex]], nil
+}
+
+// HeightAt returns the height map value of the column at the given x and z coordinates. This is the Y coordinate above
+// the highest block in the column which blocks light, as last calculated by the game. A *ChunkDataNotSavedError is
+// returned if the chunk has no Data3D or Data2D record.
+func (w *World) HeightAt(x, z, dimension int) (int, error) {
+	d, err := w.biomeData(x, z, dimension)
+	if err != nil {
+		return 0, err
+	}
+
+	sx, _, sz := worldVoxelToSubChunk(x, 0, z)
+
+	return d.heights[sz*chunkSize+sx], nil
+}
+
+// biomeData reads the Data3D record of the chunk containing the given coordinates, or its Data2D record if there is no
+// Data3D record.
+func (w *World) biomeData(x, z, dimension int) (*biomeData, error) {
+	o := subChunkOrigin(x, 0, z, dimension)
+
+	for _, tag := range []leveldb.Tag{leveldb.Data3D, leveldb.Data2D} {
+		key := leveldb.NewChunkKey(int32(o.x), int32(o.z), int32(dimension), tag).Bytes()
+
+		value, err := w.get(key)
+		if err != nil {
+			return nil, fmt.Errorf("getting %s with key '%x': %w", tag, key, err)
+		}
+
+		if value == nil {
+			continue
+		}
+
+		var d *biomeData
+		if tag == leveldb.Data3D {
+			d, err = parseData3D(value, dimension)
+		} else {
+			d, err = parseData2D(value)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("decoding %s with key '%x': %w", tag, key, err)
+		}
+
+		return d, nil
+	}
+
+	return nil, &ChunkDataNotSavedError{X: o.x, Z: o.z, Dimension: dimension}
+}
+
+// parseData2D parses a Data2D value, which is the height map followed by a biome ID byte for each column.
+func parseData2D(data []byte) (*biomeData, error) {
+	if len(data) != heightMapSize+chunkSize*chunkSize {
+		return nil, fmt.Errorf("expected %d bytes: got %d", heightMapSize+chunkSize*chunkSize, len(data))
+	}
+
+	r := bytes.NewReader(data)
+
+	d := biomeData{}
+	if err := readHeightMap(r, &d, 0); err != nil {
+		return nil, err
+	}
+
+	d.biomes2D = make([]Biome, chunkSize*chunkSize)
+	for i, b := range data[heightMapSize:] {
+		d.biomes2D[i] = Biome(b)
+	}
+
+	return &d, nil
+}
+
+// parseData3D parses a Data3D value, which is the height map followed by a biome storage for each sub chunk from the
+// bottom of the dimension.
+func parseData3D(data []byte, dimension int) (*biomeData, error) {
+	r := bytes.NewReader(data)
+
+	d := biomeData{minY: minSubChunkY(dimension)}
+	if err := readHeightMap(r, &d, d.minY*chunkSize); err != nil {
+		return nil, err
+	}
+
+	for r.Len() > 0 {
+		s, err := readBiomeStorage(r, d.storages)
+		if err != nil {
+			return nil, fmt.Errorf("reading biome storage %d: %w", len(d.storages), err)
+		}
+
+		d.storages = append(d.storages, s)
+	}
+
+	return &d, nil
+}
+
+// readHeightMap reads the height map into d. offset is added to each height to convert it to world coordinates.
+func readHeightMap(r *bytes.Reader, d *biomeData, offset int) error {
+	heights := make([]int16, chunkSize*chunkSize)
+	if err := readLittleEndian(r, heights); err != nil {
+		return fmt.Errorf("reading height map: %w", err)
+	}
+
+	for i, h := range heights {
+		d.heights[i] = int(h) + offset
+	}
+
+	return nil
+}
+
+// readBiomeStorage reads one sub chunk of Data3D biomes. previous are the storages already read, the last of which is
+// returned if the storage is marked as a copy of the one below it.
+func readBiomeStorage(r *bytes.Reader, previous []biomeStorage) (biomeStorage, error) {
+	var bitsPerBlockAndVersion byte
+	if err := readLittleEndian(r, &bitsPerBlockAndVersion); err != nil {
+		return biomeStorage{}, fmt.Errorf("reading version byte: %w", err)
+	}
+
+	if bitsPerBlockAndVersion == biomeStorageCopyLast {
+		if len(previous) == 0 {
+			return biomeStorage{}, fmt.Errorf("first storage is a copy of the previous storage")
+		}
+
+		return previous[len(previous)-1], nil
+	}
+
+	s := biomeStorage{}
+	paletteSize := int32(1)
+
+	// Storages with a single biome have no indices and no palette size
+	if bitsPerBlock := int(bitsPerBlockAndVersion >> 1); bitsPerBlock > 0 {
+		var err error
+		if s.Indices, err = unpackIndices(r, bitsPerBlock); err != nil {
+			return biomeStorage{}, err
+		}
+
+		if err := readLittleEndian(r, &paletteSize); err != nil {
+			return biomeStorage{}, fmt.Errorf("reading palette size: %w", err)
+		}
+	}
+
+	if paletteSize < 1 || int(paletteSize) > r.Len()/4 {
+		return biomeStorage{}, fmt.Errorf("invalid palette size %d", paletteSize)
+	}
+
+	palette := make([]int32, paletteSize)
+	if err := readLittleEndian(r, palette); err != nil {
+		return biomeStorage{}, fmt.Errorf("reading palette: %w", err)
+	}
+
+	s.Palette = make([]Biome, paletteSize)
+	for i, b := range palette {
+		s.Palette[i] = Biome(b)
+	}
+
+	for _, i := range s.Indices {
+		if i >= len(s.Palette) {
+			return biomeStorage{}, fmt.Errorf("index %d is outside the palette of size %d", i, len(s.Palette))
+		}
+	}
+
+	return s, nil
+}
+
+// minSubChunkY returns the Y index of the lowest sub chunk in the given dimension. The overworld extends to y -64 since
+// 1.18.
+func minSubChunkY(dimension int) int {
+	if dimension == 0 {
+		return -4
+	}
+
+	return 0
+}
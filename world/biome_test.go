@@ -0,0 +1,133 @@
+package world
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/danhale-git/mine/leveldb"
+	"github.com/danhale-git/mine/mock"
+)
+
+// biomeTestWorld returns a world with Data3D biomes in the chunk at the origin and Data2D biomes in chunk 1 0. Every
+// column has height 70.
+//
+// The Data3D biomes are plains in sub chunk -4, desert at 2 -44 3 and plains elsewhere in sub chunk -3, and a copy of
+// sub chunk -3 in sub chunk -2. The Data2D biomes are jungle at column 17 5 and ocean elsewhere.
+func biomeTestWorld(t *testing.T) *World {
+	heights := make([]int16, chunkSize*chunkSize)
+
+	data3D := bytes.Buffer{}
+	for i := range heights {
+		heights[i] = 70 + 64
+	}
+	_ = writeLittleEndian(&data3D, heights)
+
+	// Sub chunk -4 has a single biome
+	_ = writeLittleEndian(&data3D, byte(0))
+	_ = writeLittleEndian(&data3D, int32(1))
+
+	// Sub chunk -3 has two biomes
+	indices := make([]int, subChunkBlockCount)
+	i, _ := subChunkVoxelToIndex(2, 4, 3)
+	indices[i] = 1
+
+	if err := writeStateIndices(&data3D, indices, 2); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	_ = writeLittleEndian(&data3D, []int32{2, 1, 2})
+
+	// Sub chunk -2 is a copy of -3
+	_ = writeLittleEndian(&data3D, byte(biomeStorageCopyLast))
+
+	data2D := bytes.Buffer{}
+	for i := range heights {
+		heights[i] = 70
+	}
+	_ = writeLittleEndian(&data2D, heights)
+
+	biomes := make([]byte, chunkSize*chunkSize)
+	biomes[5*chunkSize+1] = 21
+	data2D.Write(biomes)
+
+	return newWorld(mock.LevelDBWithRecords(
+		mock.Record{Key: leveldb.NewChunkKey(0, 0, 0, leveldb.Data3D).Bytes(), Value: data3D.Bytes()},
+		mock.Record{Key: leveldb.NewChunkKey(1, 0, 0, leveldb.Data2D).Bytes(), Value: data2D.Bytes()},
+	))
+}
+
+func TestGetBiome(t *testing.T) {
+	w := biomeTestWorld(t)
+
+	tests := []struct {
+		x, y, z int
+		want    string
+	}{
+		{0, -64, 0, "plains"},
+		{2, -60, 3, "plains"},
+		{2, -44, 3, "desert"},
+		{2, -43, 3, "plains"},
+		{2, -28, 3, "desert"},
+		{17, 100, 5, "jungle"},
+		{17, 100, 6, "ocean"},
+	}
+
+	for _, tt := range tests {
+		b, err := w.GetBiome(tt.x, tt.y, tt.z, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if b.String() != tt.want {
+			t.Errorf("expected biome '%s' at %d %d %d: got '%s'", tt.want, tt.x, tt.y, tt.z, b)
+		}
+	}
+
+	if _, err := w.GetBiome(0, 0, 0, 0); err == nil {
+		t.Errorf("expected error getting biome above the stored sub chunks")
+	}
+
+	if _, err := w.GetBiome(40, 0, 0, 0); !errors.Is(err, &ChunkDataNotSavedError{}) {
+		t.Errorf("expected *ChunkDataNotSavedError: got %v", err)
+	}
+
+	if s := Biome(1000).String(); s != "unknown (1000)" {
+		t.Errorf("unexpected unknown biome name '%s'", s)
+	}
+}
+
+func TestHeightAt(t *testing.T) {
+	w := biomeTestWorld(t)
+
+	for _, x := range []int{3, 20} {
+		h, err := w.HeightAt(x, 4, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if h != 70 {
+			t.Errorf("expected height 70 at %d 4: got %d", x, h)
+		}
+	}
+
+	if _, err := w.HeightAt(0, 40, 0); !errors.Is(err, &ChunkDataNotSavedError{}) {
+		t.Errorf("expected *ChunkDataNotSavedError: got %v", err)
+	}
+}
+
+func TestParseData3DCorrupt(t *testing.T) {
+	data := append(make([]byte, heightMapSize), byte(biomeStorageCopyLast))
+	if _, err := parseData3D(data, 0); err == nil {
+		t.Errorf("expected error for copy of missing storage")
+	}
+
+	data = append(make([]byte, heightMapSize), 0, 5, 0, 0, 0)
+	if _, err := parseData3D(data, 0); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	data = append(make([]byte, heightMapSize), 0)
+	if _, err := parseData3D(data, 0); err == nil {
+		t.Errorf("expected error for missing palette")
+	}
+}
@@ -1,7 +1,107 @@
 package world
 
+import (
+	"encoding/json"
+
+	"github.com/danhale-git/mine/nbt"
+)
+
+// Block is a single block in the world. In JSON, States are encoded as NBT tags so their types are kept when the block
+// is decoded.
 type Block struct {
-	ID          string
-	X, Y, Z     int
-	waterLogged bool
+	ID string `json:"id"`
+
+	// States holds the block's state values keyed by state name, e.g. 'facing_direction' or 'wood_type'. Values are
+	// int8 for byte states (usually booleans), int32 for int states or string.
+	States map[string]interface{} `json:"states"`
+
+	// Version is the block version of the palette entry the block was read from. If it is zero when the block is set,
+	// the version of an existing palette entry is used.
+	Version int32 `json:"version"`
+
+	X int `json:"x"`
+	Y int `json:"y"`
+	Z int `json:"z"`
+
+	// WaterLogged is true if the block shares its space with water.
+	WaterLogged bool `json:"waterLogged"`
+
+	// Liquid is the block in the second storage layer, or nil if that layer is air or missing. It is usually water for
+	// water logged blocks but may be another liquid or snow. It is only populated by GetBlock.
+	Liquid *Block `json:"liquid,omitempty"`
+
+	// Entity is the block entity compound holding extra data for blocks such as chests, signs and spawners, or nil if
+	// the block has none. It is only populated by GetBlockWithEntity.
+	Entity *nbt.NBTTag `json:"entity,omitempty"`
+}
+
+// MarshalJSON encodes the block with its States as a list of NBT tags in name order.
+func (b Block) MarshalJSON() ([]byte, error) {
+	type block Block
+
+	state, err := nbt.NewBlockState(b.ID, b.States, b.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	states, _ := state.Child("states")
+
+	return json.Marshal(struct {
+		block
+		States []nbt.NBTTag `json:"states"`
+	}{block(b), states.Value.([]nbt.NBTTag)})
+}
+
+// UnmarshalJSON decodes a block encoded by MarshalJSON, restoring the int8, int32 and string types of its States.
+func (b *Block) UnmarshalJSON(data []byte) error {
+	type block Block
+
+	v := struct {
+		*block
+		States []nbt.NBTTag `json:"states"`
+	}{block: (*block)(b)}
+
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	b.States = make(map[string]interface{}, len(v.States))
+	for _, s := range v.States {
+		b.States[s.Name] = s.Value
+	}
+
+	return nil
+}
+
+// StateInt returns the value of the named int or byte state. The boolean is false if the block has no such state.
+func (b Block) StateInt(name string) (int, bool) {
+	switch v := b.States[name].(type) {
+	case int32:
+		return int(v), true
+	case int8:
+		return int(v), true
+	case int:
+		return v, true
+	}
+
+	return 0, false
+}
+
+// StateBool returns the value of the named byte state as a boolean. The second boolean is false if the block has no
+// such state.
+func (b Block) StateBool(name string) (bool, bool) {
+	switch v := b.States[name].(type) {
+	case int8:
+		return v != 0, true
+	case bool:
+		return v, true
+	}
+
+	return false, false
+}
+
+// StateString returns the value of the named string state. The boolean is false if the block has no such state.
+func (b Block) StateString(name string) (string, bool) {
+	v, ok := b.States[name].(string)
+	return v, ok
 }
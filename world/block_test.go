@@ -0,0 +1,89 @@
+package world
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/danhale-git/mine/mock"
+)
+
+func TestBlockStates(t *testing.T) {
+	b := Block{
+		ID: "minecraft:wooden_door",
+		States: map[string]interface{}{
+			"direction":       int32(3),
+			"upper_block_bit": int8(1),
+			"door_hinge_bit":  int8(0),
+			"wood_type":       "oak",
+		},
+	}
+
+	if v, ok := b.StateInt("direction"); !ok || v != 3 {
+		t.Errorf("unexpected value for direction: %d, %t", v, ok)
+	}
+
+	if v, ok := b.StateBool("upper_block_bit"); !ok || !v {
+		t.Errorf("unexpected value for upper_block_bit: %t, %t", v, ok)
+	}
+
+	if v, ok := b.StateBool("door_hinge_bit"); !ok || v {
+		t.Errorf("unexpected value for door_hinge_bit: %t, %t", v, ok)
+	}
+
+	if v, ok := b.StateString("wood_type"); !ok || v != "oak" {
+		t.Errorf("unexpected value for wood_type: '%s', %t", v, ok)
+	}
+
+	if _, ok := b.StateString("direction"); ok {
+		t.Errorf("expected direction not to be a string state")
+	}
+
+	if _, ok := b.StateInt("missing"); ok {
+		t.Errorf("expected missing state not to be found")
+	}
+}
+
+func TestBlockJSON(t *testing.T) {
+	b := Block{
+		ID:          "minecraft:fence",
+		States:      map[string]interface{}{"wood_type": "oak", "post_bit": int8(1), "age": int32(3)},
+		Version:     defaultBlockVersion,
+		WaterLogged: true,
+		Liquid:      &Block{ID: waterID, States: map[string]interface{}{"liquid_depth": int32(0)}, Version: defaultBlockVersion},
+	}
+
+	j, err := json.Marshal(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	got := Block{}
+	if err := json.Unmarshal(j, &got); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !reflect.DeepEqual(got, b) {
+		t.Errorf("block changed after json round trip: expected %+v: got %+v", b, got)
+	}
+
+	if v, ok := got.Liquid.StateInt("liquid_depth"); !ok || v != 0 {
+		t.Errorf("unexpected liquid_depth after json round trip: %d, %t", v, ok)
+	}
+
+	// The decoded block can be set
+	w := newWorld(mock.ValidLevelDB())
+
+	if err := w.SetBlock(0, 0, 0, 0, got); err != nil {
+		t.Fatalf("unexpected error setting decoded block: %s", err)
+	}
+
+	set, err := w.GetBlock(0, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if set.ID != b.ID || !reflect.DeepEqual(set.States, b.States) || !set.WaterLogged {
+		t.Errorf("decoded block was not set: expected %+v: got %+v", b, set)
+	}
+}
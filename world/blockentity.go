@@ -0,0 +1,273 @@
+package world
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/danhale-git/mine/leveldb"
+	"github.com/danhale-git/mine/nbt"
+)
+
+// BlockEntities returns the block entity compounds stored for the chunk at the given chunk coordinates, in the order
+// they are stored. Each has 'id', 'x', 'y' and 'z' tags giving its type and world coordinates. It returns an empty slice
+// if the chunk has no block entities, or a *CorruptBlockEntitiesError if they can't be parsed.
+func (w *World) BlockEntities(x, z, dimension int) ([]nbt.NBTTag, error) {
+	key := leveldb.NewChunkKey(int32(x), int32(z), int32(dimension), leveldb.BlockEntity).Bytes()
+
+	value, err := w.get(key)
+	if err != nil {
+		return nil, fmt.Errorf("getting block entities with key '%x': %w", key, err)
+	}
+
+	entities, err := parseCompounds(value)
+	if err != nil {
+		return nil, &CorruptBlockEntitiesError{Key: key, Err: err}
+	}
+
+	return entities, nil
+}
+
+// GetBlockEntity returns the block entity compound of the block at the given coordinates, or nil if it has none. The
+// block entities of recently used chunks are held in memory indexed by position, as limited by the CacheConfig, until
+// they are edited or the World is flushed. The returned compound must not be modified.
+func (w *World) GetBlockEntity(x, y, z, dimension int) (*nbt.NBTTag, error) {
+	o := subChunkOrigin(x, y, z, dimension)
+
+	entities, err := w.chunkBlockEntities(struct{ x, z, d int }{o.x, o.z, dimension})
+	if err != nil {
+		return nil, err
+	}
+
+	e, ok := entities[Position{x, y, z}]
+	if !ok {
+		return nil, nil
+	}
+
+	return &e, nil
+}
+
+// blockEntityLoad is the block entities of a chunk being read from the database. done is closed when entities and err
+// are set. stale is set if the chunk's block entities are edited or flushed during the load, so the result is not
+// cached.
+type blockEntityLoad struct {
+	done     chan struct{}
+	entities map[Position]nbt.NBTTag
+	err      error
+	stale    bool
+}
+
+// chunkBlockEntities returns the block entities of the given chunk indexed by position, reading them if they are not
+// cached. Concurrent calls for the same chunk share one read. Block entities with no position are not indexed.
+func (w *World) chunkBlockEntities(chunk struct{ x, z, d int }) (map[Position]nbt.NBTTag, error) {
+	w.blockEntityMu.Lock()
+
+	if entities, ok := w.blockEntities.get(chunk); ok {
+		w.blockEntityMu.Unlock()
+		return entities, nil
+	}
+
+	if l, ok := w.blockEntityLoads[chunk]; ok {
+		w.blockEntityMu.Unlock()
+
+		<-l.done
+		return l.entities, l.err
+	}
+
+	l := &blockEntityLoad{done: make(chan struct{})}
+	w.blockEntityLoads[chunk] = l
+
+	w.blockEntityMu.Unlock()
+
+	list, err := w.BlockEntities(chunk.x, chunk.z, chunk.d)
+	if err == nil {
+		l.entities = make(map[Position]nbt.NBTTag, len(list))
+		for _, e := range list {
+			if p, ok := BlockEntityPosition(&e); ok {
+				l.entities[p] = e
+			}
+		}
+	}
+	l.err = err
+
+	w.blockEntityMu.Lock()
+
+	if l.err == nil && !l.stale {
+		w.blockEntities.add(chunk, l.entities)
+	}
+	delete(w.blockEntityLoads, chunk)
+
+	w.blockEntityMu.Unlock()
+
+	close(l.done)
+
+	return l.entities, l.err
+}
+
+// dropBlockEntities drops the cached block entities of the given chunk, including any being read.
+func (w *World) dropBlockEntities(chunk struct{ x, z, d int }) {
+	w.blockEntityMu.Lock()
+	defer w.blockEntityMu.Unlock()
+
+	w.blockEntities.remove(chunk)
+
+	if l, ok := w.blockEntityLoads[chunk]; ok {
+		l.stale = true
+	}
+}
+
+// SetBlockEntity sets the block entity of the block at the given coordinates, replacing any existing one, and writes the
+// chunk's block entities back to the database. e must be a compound with a string 'id' tag such as 'Chest' or 'Sign'.
+// Its 'x', 'y' and 'z' tags are set to the given coordinates.
+func (w *World) SetBlockEntity(x, y, z, dimension int, e nbt.NBTTag) error {
+	if e.Type != nbt.TagCompound {
+		return fmt.Errorf("block entity has type %d: expected compound", e.Type)
+	}
+
+	if id, ok := e.Child("id"); !ok || id.Type != nbt.TagString {
+		return fmt.Errorf("block entity has no string 'id' tag")
+	}
+
+	// Copy the top level tags so the caller's compound is not changed
+	e.Value = append([]nbt.NBTTag{}, e.Value.([]nbt.NBTTag)...)
+
+	for i, v := range []int{x, y, z} {
+		name := []string{"x", "y", "z"}[i]
+		if err := e.SetChild(nbt.NBTTag{Type: nbt.TagInt, Name: name, Value: int32(v)}); err != nil {
+			return err
+		}
+	}
+
+	return w.editBlockEntities(x, y, z, dimension, func(entities []nbt.NBTTag, i int) []nbt.NBTTag {
+		if i < 0 {
+			return append(entities, e)
+		}
+
+		entities[i] = e
+		return entities
+	})
+}
+
+// RemoveBlockEntity removes the block entity of the block at the given coordinates and writes the chunk's block
+// entities back to the database. The boolean is false if the block had no block entity.
+func (w *World) RemoveBlockEntity(x, y, z, dimension int) (bool, error) {
+	removed := false
+
+	err := w.editBlockEntities(x, y, z, dimension, func(entities []nbt.NBTTag, i int) []nbt.NBTTag {
+		if i < 0 {
+			return entities
+		}
+
+		removed = true
+		return append(entities[:i], entities[i+1:]...)
+	})
+
+	return removed, err
+}
+
+// editBlockEntities reads the block entities of the chunk containing the given coordinates and calls edit with them and
+// the index of the block entity at the coordinates, or -1 if there is none. The block entities returned by edit are
+// written back to the database, or the record is deleted if there are none.
+func (w *World) editBlockEntities(x, y, z, dimension int, edit func(entities []nbt.NBTTag, i int) []nbt.NBTTag) error {
+	w.entityMu.Lock()
+	defer w.entityMu.Unlock()
+
+	o := subChunkOrigin(x, y, z, dimension)
+
+	entities, err := w.BlockEntities(o.x, o.z, dimension)
+	if err != nil {
+		return err
+	}
+
+	index := -1
+	for i := range entities {
+		if p, ok := BlockEntityPosition(&entities[i]); ok && p == (Position{x, y, z}) {
+			index = i
+			break
+		}
+	}
+
+	key := leveldb.NewChunkKey(int32(o.x), int32(o.z), int32(dimension), leveldb.BlockEntity).Bytes()
+
+	err = w.putCompounds(key, edit(entities, index))
+
+	w.dropBlockEntities(struct{ x, z, d int }{o.x, o.z, dimension})
+
+	return err
+}
+
+// putCompounds writes the tags as a sequence of root compound tags with the given key, or deletes the record if there
+// are no tags.
+func (w *World) putCompounds(key []byte, tags []nbt.NBTTag) error {
+	if len(tags) == 0 {
+		if err := w.db.Delete(key); err != nil {
+			return fmt.Errorf("deleting record with key '%x': %w", key, err)
+		}
+		return nil
+	}
+
+	value, err := writeCompounds(tags)
+	if err != nil {
+		return fmt.Errorf("encoding record with key '%x': %w", key, err)
+	}
+
+	if err := w.db.Put(key, value); err != nil {
+		return fmt.Errorf("putting record with key '%x': %w", key, err)
+	}
+
+	return nil
+}
+
+// BlockEntityPosition returns the world coordinates of the given block entity. The boolean is false if it has no int
+// 'x', 'y' and 'z' tags.
+func BlockEntityPosition(t *nbt.NBTTag) (Position, bool) {
+	var c [3]int32
+
+	for i, name := range []string{"x", "y", "z"} {
+		tag, ok := t.Child(name)
+		if !ok {
+			return Position{}, false
+		}
+
+		if c[i], ok = tag.Value.(int32); !ok {
+			return Position{}, false
+		}
+	}
+
+	return Position{int(c[0]), int(c[1]), int(c[2])}, true
+}
+
+// parseCompounds parses a record which is a sequence of root compound tags, such as a BlockEntity or Entity record.
+func parseCompounds(data []byte) ([]nbt.NBTTag, error) {
+	r := bytes.NewReader(data)
+	tags := make([]nbt.NBTTag, 0)
+
+	for r.Len() > 0 {
+		offset := r.Size() - int64(r.Len())
+
+		t, err := nbt.Read(r)
+		if err != nil {
+			return nil, fmt.Errorf("reading tag at offset %d: %w", offset, err)
+		}
+
+		if t.Type != nbt.TagCompound {
+			return nil, fmt.Errorf("tag at offset %d has type %d: expected compound", offset, t.Type)
+		}
+
+		tags = append(tags, t)
+	}
+
+	return tags, nil
+}
+
+// writeCompounds encodes tags as a sequence of root compound tags. It is the inverse of parseCompounds.
+func writeCompounds(tags []nbt.NBTTag) ([]byte, error) {
+	buf := bytes.Buffer{}
+
+	for _, t := range tags {
+		if err := nbt.Write(&buf, t); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,231 @@
+package world
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/danhale-git/mine/leveldb"
+	"github.com/danhale-git/mine/mock"
+	"github.com/danhale-git/mine/nbt"
+)
+
+func testBlockEntity(id string, x, y, z int32, tags ...nbt.NBTTag) nbt.NBTTag {
+	return nbt.NBTTag{Type: nbt.TagCompound, Value: append([]nbt.NBTTag{
+		{Type: nbt.TagString, Name: "id", Value: id},
+		{Type: nbt.TagInt, Name: "x", Value: x},
+		{Type: nbt.TagInt, Name: "y", Value: y},
+		{Type: nbt.TagInt, Name: "z", Value: z},
+	}, tags...)}
+}
+
+// blockEntityTestWorld returns a world with a chest and a sign in the chunk at the origin.
+func blockEntityTestWorld() (*World, *mock.LevelDB) {
+	db := mock.LevelDBWithRecords(
+		mock.Record{Key: leveldb.NewSubChunkKey(0, 0, 0, 0).Bytes(), Value: mock.SubChunkValue},
+		mock.Record{Key: leveldb.NewChunkKey(0, 0, 0, leveldb.BlockEntity).Bytes(), NBT: []nbt.NBTTag{
+			testBlockEntity("Chest", 1, 2, 3, nbt.NBTTag{Type: nbt.TagList, Name: "Items", Value: nbt.List{Type: nbt.TagCompound}}),
+			testBlockEntity("Sign", 4, 5, 6, nbt.NBTTag{Type: nbt.TagString, Name: "Text", Value: "hello"}),
+		}},
+	)
+
+	return newWorld(db), db
+}
+
+func TestGetBlockEntity(t *testing.T) {
+	w, _ := blockEntityTestWorld()
+
+	b, err := w.GetBlockWithEntity(4, 5, 6, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if b.Entity == nil {
+		t.Fatalf("expected block to have a block entity")
+	}
+
+	if text, _ := b.Entity.Child("Text"); text == nil || text.Value != "hello" {
+		t.Errorf("unexpected sign text %+v", text)
+	}
+
+	b, err = w.GetBlockWithEntity(4, 5, 7, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if b.Entity != nil {
+		t.Errorf("expected no block entity: got %+v", b.Entity)
+	}
+
+	// GetBlock doesn't read block entities
+	b, err = w.GetBlock(4, 5, 6, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if b.Entity != nil {
+		t.Errorf("expected GetBlock not to return a block entity: got %+v", b.Entity)
+	}
+}
+
+func TestBlockEntities(t *testing.T) {
+	w, db := blockEntityTestWorld()
+
+	entities, err := w.BlockEntities(0, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := []Position{{1, 2, 3}, {4, 5, 6}}
+
+	if len(entities) != len(want) {
+		t.Fatalf("expected %d block entities: got %d", len(want), len(entities))
+	}
+
+	for i := range entities {
+		if p, ok := BlockEntityPosition(&entities[i]); !ok || p != want[i] {
+			t.Errorf("expected block entity at %+v: got %+v", want[i], p)
+		}
+	}
+
+	if entities, err := w.BlockEntities(1, 0, 0); err != nil || len(entities) != 0 {
+		t.Errorf("expected no block entities: got %d, %v", len(entities), err)
+	}
+
+	if err := db.Put(leveldb.NewChunkKey(0, 0, 0, leveldb.BlockEntity).Bytes(), []byte{nbt.TagCompound, 5}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var corrupt *CorruptBlockEntitiesError
+	if _, err := w.BlockEntities(0, 0, 0); !errors.As(err, &corrupt) {
+		t.Errorf("expected *CorruptBlockEntitiesError: got %v", err)
+	}
+}
+
+func TestBlockEntityCache(t *testing.T) {
+	w, db := blockEntityTestWorld()
+	key := leveldb.NewChunkKey(0, 0, 0, leveldb.BlockEntity).Bytes()
+
+	if e, err := w.GetBlockEntity(1, 2, 3, 0); err != nil || e == nil {
+		t.Fatalf("expected chest block entity: got %v, %v", e, err)
+	}
+
+	// Cached block entities are not read again
+	if err := db.Put(key, []byte{nbt.TagCompound, 5}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if e, err := w.GetBlockEntity(1, 2, 3, 0); err != nil || e == nil {
+		t.Errorf("expected cached chest block entity: got %v, %v", e, err)
+	}
+
+	// A corrupt record doesn't stop the block being read without its block entity
+	w.Flush()
+
+	var corrupt *CorruptBlockEntitiesError
+
+	if _, err := w.GetBlockWithEntity(4, 5, 6, 0); !errors.As(err, &corrupt) {
+		t.Errorf("expected *CorruptBlockEntitiesError: got %v", err)
+	}
+
+	if b, err := w.GetBlock(4, 5, 6, 0); err != nil || b.ID == "" {
+		t.Errorf("expected block: got %+v, %v", b, err)
+	}
+
+	// Only the most recently used chunks are held
+	w, _ = blockEntityTestWorld()
+	w.SetCacheConfig(CacheConfig{MaxBlockEntityChunks: 1})
+
+	for _, x := range []int{1, 17, 33} {
+		if _, err := w.GetBlockEntity(x, 2, 3, 0); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	if n := w.blockEntities.order.Len(); n != 1 {
+		t.Errorf("expected block entities of 1 chunk to be held: got %d", n)
+	}
+
+	// Edits drop the cached block entities
+	w, _ = blockEntityTestWorld()
+
+	if e, err := w.GetBlockEntity(7, 8, 9, 0); err != nil || e != nil {
+		t.Fatalf("expected no block entity: got %v, %v", e, err)
+	}
+
+	if err := w.SetBlockEntity(7, 8, 9, 0, testBlockEntity("MobSpawner", 0, 0, 0)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if e, err := w.GetBlockEntity(7, 8, 9, 0); err != nil || e == nil {
+		t.Errorf("expected new block entity: got %v, %v", e, err)
+	}
+}
+
+func TestSetBlockEntity(t *testing.T) {
+	w, _ := blockEntityTestWorld()
+
+	chest := testBlockEntity("Chest", 0, 0, 0, nbt.NBTTag{Type: nbt.TagString, Name: "LootTable", Value: "loot_tables/chests/village/village_two_room_house.json"})
+
+	// Replaces the existing chest, setting its coordinates
+	if err := w.SetBlockEntity(1, 2, 3, 0, chest); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Adds a new block entity
+	if err := w.SetBlockEntity(7, 8, 9, 0, testBlockEntity("MobSpawner", 0, 0, 0)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if p, _ := BlockEntityPosition(&chest); p != (Position{}) {
+		t.Errorf("the given block entity was changed")
+	}
+
+	entities, err := w.BlockEntities(0, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(entities) != 3 {
+		t.Fatalf("expected 3 block entities: got %d", len(entities))
+	}
+
+	e, err := w.GetBlockEntity(1, 2, 3, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if e == nil {
+		t.Fatalf("expected chest block entity")
+	}
+
+	if _, ok := e.Child("LootTable"); !ok {
+		t.Errorf("expected chest to be replaced: got %+v", e)
+	}
+
+	if err := w.SetBlockEntity(1, 2, 3, 0, nbt.NBTTag{Type: nbt.TagCompound, Value: []nbt.NBTTag{}}); err == nil {
+		t.Errorf("expected error setting block entity with no id")
+	}
+}
+
+func TestRemoveBlockEntity(t *testing.T) {
+	w, db := blockEntityTestWorld()
+
+	for _, p := range []Position{{1, 2, 3}, {4, 5, 6}} {
+		removed, err := w.RemoveBlockEntity(p.X, p.Y, p.Z, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if !removed {
+			t.Errorf("expected block entity at %+v to be removed", p)
+		}
+	}
+
+	if removed, err := w.RemoveBlockEntity(1, 2, 3, 0); err != nil || removed {
+		t.Errorf("expected nothing to be removed: got %t, %v", removed, err)
+	}
+
+	if _, err := db.Get(leveldb.NewChunkKey(0, 0, 0, leveldb.BlockEntity).Bytes()); err == nil {
+		t.Errorf("expected empty block entity record to be deleted")
+	}
+}
@@ -0,0 +1,272 @@
+package world
+
+import (
+	"container/list"
+	"strconv"
+
+	"github.com/danhale-git/mine/nbt"
+)
+
+// DefaultCacheConfig is the cache configuration used by New. Parsed sub chunks take roughly 40KB each, so the default
+// limit holds around 160MB.
+var DefaultCacheConfig = CacheConfig{MaxEntries: 4096, MaxBlockEntityChunks: 1024}
+
+// CacheConfig limits the parsed sub chunks and block entities a World holds in memory. When a limit is exceeded the
+// least recently used sub chunks or chunks are evicted. A zero limit is unlimited.
+type CacheConfig struct {
+	MaxEntries int
+
+	// MaxBytes limits the estimated memory held by parsed sub chunks, which is mostly their block indices. A sub chunk
+	// is measured when it is read, so changes made to it later are not counted.
+	MaxBytes int
+
+	// MaxBlockEntityChunks limits the number of chunks whose block entities are held for GetBlockEntity.
+	MaxBlockEntityChunks int
+}
+
+// CacheStats are the counters of a World's sub chunk cache. Hits counts sub chunks found in the cache or shared with
+// another call which is using them, and Misses counts sub chunks read from the database or waited for while another call
+// reads them.
+type CacheStats struct {
+	Hits, Misses, Evictions uint64
+
+	Entries, Bytes int
+}
+
+type cacheEntry struct {
+	origin struct{ x, y, z, d int }
+	sc     *subChunkData
+	size   int
+}
+
+// subChunkCache is a least recently used cache of parsed sub chunks.
+type subChunkCache struct {
+	config  CacheConfig
+	entries map[struct{ x, y, z, d int }]*list.Element
+	order   *list.List // Most recently used at the front
+	stats   CacheStats
+}
+
+func newSubChunkCache(c CacheConfig) *subChunkCache {
+	return &subChunkCache{
+		config:  c,
+		entries: make(map[struct{ x, y, z, d int }]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// get returns the cached sub chunk with the given origin and marks it as recently used. Hits and misses are counted by
+// the caller.
+func (c *subChunkCache) get(origin struct{ x, y, z, d int }) (*subChunkData, bool) {
+	e, ok := c.entries[origin]
+	if !ok {
+		return nil, false
+	}
+
+	c.order.MoveToFront(e)
+
+	return e.Value.(*cacheEntry).sc, true
+}
+
+// add caches the sub chunk with the given origin, evicting others if the cache is over its limits. size is the estimated
+// memory held by the sub chunk, as returned by memorySize.
+func (c *subChunkCache) add(origin struct{ x, y, z, d int }, sc *subChunkData, size int) {
+	c.remove(origin)
+
+	c.entries[origin] = c.order.PushFront(&cacheEntry{origin, sc, size})
+	c.stats.Entries++
+	c.stats.Bytes += size
+
+	c.evict()
+}
+
+// remove drops the sub chunk with the given origin, if it is cached.
+func (c *subChunkCache) remove(origin struct{ x, y, z, d int }) {
+	e, ok := c.entries[origin]
+	if !ok {
+		return
+	}
+
+	c.order.Remove(e)
+	delete(c.entries, origin)
+
+	c.stats.Entries--
+	c.stats.Bytes -= e.Value.(*cacheEntry).size
+}
+
+// evict removes least recently used entries until the cache is within its limits. The most recently added entry is
+// always kept.
+func (c *subChunkCache) evict() {
+	for c.order.Len() > 1 && c.overLimit() {
+		c.remove(c.order.Back().Value.(*cacheEntry).origin)
+		c.stats.Evictions++
+	}
+}
+
+func (c *subChunkCache) overLimit() bool {
+	return (c.config.MaxEntries > 0 && c.stats.Entries > c.config.MaxEntries) ||
+		(c.config.MaxBytes > 0 && c.stats.Bytes > c.config.MaxBytes)
+}
+
+// clear removes all entries. Counters are not reset.
+func (c *subChunkCache) clear() {
+	c.entries = make(map[struct{ x, y, z, d int }]*list.Element)
+	c.order.Init()
+	c.stats.Entries = 0
+	c.stats.Bytes = 0
+}
+
+type blockEntityCacheEntry struct {
+	chunk    struct{ x, z, d int }
+	entities map[Position]nbt.NBTTag
+}
+
+// blockEntityCache is a least recently used cache of the block entities of each chunk, indexed by position.
+type blockEntityCache struct {
+	max     int
+	entries map[struct{ x, z, d int }]*list.Element
+	order   *list.List // Most recently used at the front
+}
+
+func newBlockEntityCache(max int) *blockEntityCache {
+	return &blockEntityCache{
+		max:     max,
+		entries: make(map[struct{ x, z, d int }]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// get returns the cached block entities of the given chunk and marks them as recently used.
+func (c *blockEntityCache) get(chunk struct{ x, z, d int }) (map[Position]nbt.NBTTag, bool) {
+	e, ok := c.entries[chunk]
+	if !ok {
+		return nil, false
+	}
+
+	c.order.MoveToFront(e)
+
+	return e.Value.(*blockEntityCacheEntry).entities, true
+}
+
+// add caches the block entities of the given chunk, evicting others if the cache is over its limit.
+func (c *blockEntityCache) add(chunk struct{ x, z, d int }, entities map[Position]nbt.NBTTag) {
+	c.remove(chunk)
+
+	c.entries[chunk] = c.order.PushFront(&blockEntityCacheEntry{chunk, entities})
+
+	c.evict()
+}
+
+// remove drops the block entities of the given chunk, if they are cached.
+func (c *blockEntityCache) remove(chunk struct{ x, z, d int }) {
+	if e, ok := c.entries[chunk]; ok {
+		c.order.Remove(e)
+		delete(c.entries, chunk)
+	}
+}
+
+// evict removes least recently used entries until the cache is within its limit.
+func (c *blockEntityCache) evict() {
+	for c.max > 0 && c.order.Len() > c.max {
+		c.remove(c.order.Back().Value.(*blockEntityCacheEntry).chunk)
+	}
+}
+
+// clear removes all entries.
+func (c *blockEntityCache) clear() {
+	c.entries = make(map[struct{ x, z, d int }]*list.Element)
+	c.order.Init()
+}
+
+// memorySize returns an estimate of the memory in bytes held by the parsed sub chunk: its block indices and palettes.
+func (s *subChunkData) memorySize() int {
+	size := 0
+
+	for _, l := range s.Layers {
+		size += len(l.Indices) * intSize
+
+		for _, t := range l.Palette {
+			size += tagMemorySize(t)
+		}
+	}
+
+	return size
+}
+
+// intSize is the size in bytes of an int.
+const intSize = strconv.IntSize / 8
+
+// tagMemorySize returns an estimate of the memory in bytes held by the tag and its children.
+func tagMemorySize(t nbt.NBTTag) int {
+	// The type, name and value headers
+	size := 40 + len(t.Name)
+
+	switch v := t.Value.(type) {
+	case string:
+		size += len(v)
+	case []byte:
+		size += len(v)
+	case []int32:
+		size += len(v) * 4
+	case []int64:
+		size += len(v) * 8
+	case []nbt.NBTTag:
+		for _, c := range v {
+			size += tagMemorySize(c)
+		}
+	case nbt.List:
+		for _, e := range v.Values {
+			size += tagMemorySize(nbt.NBTTag{Type: v.Type, Value: e})
+		}
+	}
+
+	return size
+}
+
+// SetCacheConfig changes the limits of the sub chunk and block entity caches, evicting entries if they are exceeded.
+func (w *World) SetCacheConfig(c CacheConfig) {
+	w.mu.Lock()
+
+	w.cache.config = c
+	w.cache.evict()
+	w.mu.Unlock()
+
+	w.blockEntityMu.Lock()
+	defer w.blockEntityMu.Unlock()
+
+	w.blockEntities.max = c.MaxBlockEntityChunks
+	w.blockEntities.evict()
+}
+
+// CacheStats returns the sub chunk cache counters.
+func (w *World) CacheStats() CacheStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.cache.stats
+}
+
+// Flush drops all cached sub chunks and block entities, so they are read from the database again when next used.
+// Changes are written to the database when they are made, so nothing is lost. Sub chunks in use are dropped as described
+// by Invalidate.
+func (w *World) Flush() {
+	w.mu.Lock()
+	w.cache.clear()
+	w.mu.Unlock()
+
+	w.blockEntityMu.Lock()
+	w.blockEntities.clear()
+	for _, l := range w.blockEntityLoads {
+		l.stale = true
+	}
+	w.blockEntityMu.Unlock()
+}
+
+// Invalidate drops the cached sub chunk containing the given coordinates, if any. A sub chunk which is in use by another
+// call is still shared with new calls until that call finishes.
+func (w *World) Invalidate(x, y, z, dimension int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.cache.remove(subChunkOrigin(x, y, z, dimension))
+}
@@ -0,0 +1,110 @@
+package world
+
+import (
+	"testing"
+
+	"github.com/danhale-git/mine/mock"
+)
+
+func TestCacheEviction(t *testing.T) {
+	w := newWorld(mock.ValidLevelDB())
+	w.SetCacheConfig(CacheConfig{MaxEntries: 2})
+
+	get := func(x int) {
+		if _, err := w.GetBlock(x, 0, 0, 0); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	get(0)
+	get(16)
+	get(0)  // Hit, 0 is now most recently used
+	get(32) // Evicts 16
+	get(0)  // Hit
+	get(16) // Miss, evicts 32
+
+	want := CacheStats{Hits: 2, Misses: 4, Evictions: 2, Entries: 2, Bytes: 2 * testSubChunkSize(t)}
+	if got := w.CacheStats(); got != want {
+		t.Errorf("unexpected cache stats: expected %+v: got %+v", want, got)
+	}
+}
+
+func TestCacheStatsPinned(t *testing.T) {
+	w := newWorld(mock.ValidLevelDB())
+	w.SetCacheConfig(CacheConfig{MaxEntries: 1})
+
+	// Sub chunk 0 stays in use while it is evicted
+	_, release, err := w.subChunk(0, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer release()
+
+	for _, x := range []int{16, 0} {
+		if _, err := w.GetBlock(x, 0, 0, 0); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	if s := w.CacheStats(); s.Hits != 1 || s.Misses != 2 || s.Evictions != 1 {
+		t.Errorf("expected the pinned sub chunk to be counted as a hit: got %+v", s)
+	}
+}
+
+// testSubChunkSize returns the estimated memory held by the parsed mock sub chunk.
+func testSubChunkSize(t *testing.T) int {
+	sc, err := parseSubChunk(mock.SubChunkValue, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	return sc.memorySize()
+}
+
+func TestSubChunkMemorySize(t *testing.T) {
+	// The indices alone take far more memory than the encoded value
+	if size := testSubChunkSize(t); size < subChunkBlockCount*intSize || size < 4*len(mock.SubChunkValue) {
+		t.Errorf("estimated size %d is less than the memory held by the indices", size)
+	}
+}
+
+func TestCacheMaxBytes(t *testing.T) {
+	w := newWorld(mock.ValidLevelDB())
+	w.SetCacheConfig(CacheConfig{MaxBytes: testSubChunkSize(t)})
+
+	for x := 0; x < 64; x += 16 {
+		if _, err := w.GetBlock(x, 0, 0, 0); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	if s := w.CacheStats(); s.Entries != 1 || s.Evictions != 3 {
+		t.Errorf("expected 1 entry and 3 evictions: got %+v", s)
+	}
+}
+
+func TestCacheInvalidate(t *testing.T) {
+	w := newWorld(mock.ValidLevelDB())
+
+	for x := 0; x < 64; x += 16 {
+		if _, err := w.GetBlock(x, 0, 0, 0); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	w.Invalidate(20, 5, 5, 0)
+
+	if s := w.CacheStats(); s.Entries != 3 {
+		t.Errorf("expected 3 entries after invalidating: got %d", s.Entries)
+	}
+
+	if _, ok := w.cache.get(subChunkOrigin(16, 0, 0, 0)); ok {
+		t.Errorf("invalidated sub chunk is still cached")
+	}
+
+	w.Flush()
+
+	if s := w.CacheStats(); s.Entries != 0 || s.Bytes != 0 {
+		t.Errorf("expected empty cache after flushing: got %+v", s)
+	}
+}
@@ -0,0 +1,63 @@
+package world
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/danhale-git/mine/leveldb"
+)
+
+// Chunk is a chunk which is saved in the world database.
+type Chunk struct {
+	X, Z      int // Chunk coordinates, which are world coordinates divided by 16
+	Dimension int
+
+	// SubChunks are the Y indices of the sub chunks stored for this chunk, in ascending order.
+	SubChunks []int
+}
+
+// Chunks returns every chunk in the given dimension which has at least one record in the world database, ordered by x
+// then z.
+func (w *World) Chunks(dimension int) ([]Chunk, error) {
+	chunks := make(map[struct{ x, z int }]*Chunk)
+
+	err := w.db.Iterate(nil, func(b []byte) error {
+		k, err := leveldb.ParseKey(b)
+		if err != nil || k.Type != leveldb.ChunkKey || int(k.Dimension) != dimension {
+			return nil
+		}
+
+		coords := struct{ x, z int }{int(k.X), int(k.Z)}
+
+		c, ok := chunks[coords]
+		if !ok {
+			c = &Chunk{X: coords.x, Z: coords.z, Dimension: dimension, SubChunks: []int{}}
+			chunks[coords] = c
+		}
+
+		if k.Tag == leveldb.SubChunkPrefix {
+			c.SubChunks = append(c.SubChunks, int(k.SubChunkY))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("iterating keys: %w", err)
+	}
+
+	list := make([]Chunk, 0, len(chunks))
+
+	for _, c := range chunks {
+		sort.Ints(c.SubChunks)
+		list = append(list, *c)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].X != list[j].X {
+			return list[i].X < list[j].X
+		}
+		return list[i].Z < list[j].Z
+	})
+
+	return list, nil
+}
@@ -0,0 +1,46 @@
+package world
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/danhale-git/mine/leveldb"
+	"github.com/danhale-git/mine/mock"
+)
+
+func TestChunks(t *testing.T) {
+	db := mock.LevelDBWithKeys(
+		leveldb.NewSubChunkKey(0, 0, 0, 0).Bytes(),
+		leveldb.NewSubChunkKey(0, 0, 0, -4).Bytes(),
+		leveldb.NewSubChunkKey(0, 0, 0, 2).Bytes(),
+		leveldb.NewChunkKey(0, 0, 0, leveldb.Version).Bytes(),
+		leveldb.NewChunkKey(-1, 5, 0, leveldb.Version).Bytes(),
+		leveldb.NewSubChunkKey(3, 3, 1, 0).Bytes(),
+		leveldb.NewNameKey(leveldb.LocalPlayer).Bytes(),
+	)
+
+	w := newWorld(db)
+
+	chunks, err := w.Chunks(0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []Chunk{
+		{X: -1, Z: 5, Dimension: 0, SubChunks: []int{}},
+		{X: 0, Z: 0, Dimension: 0, SubChunks: []int{-4, 0, 2}},
+	}
+
+	if !reflect.DeepEqual(chunks, expected) {
+		t.Errorf("unexpected chunks: expected %+v: got %+v", expected, chunks)
+	}
+
+	chunks, err = w.Chunks(1)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(chunks) != 1 || chunks[0].X != 3 || chunks[0].Z != 3 {
+		t.Errorf("unexpected nether chunks: %+v", chunks)
+	}
+}
@@ -0,0 +1,267 @@
+package world
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/danhale-git/mine/leveldb"
+	"github.com/danhale-git/mine/mock"
+)
+
+// countingLevelDB counts calls to Get for sub chunk keys and delays them, so concurrent requests for the same sub chunk
+// overlap.
+type countingLevelDB struct {
+	*mock.LevelDB
+	gets int32
+}
+
+func (c *countingLevelDB) Get(key []byte) ([]byte, error) {
+	if k, err := leveldb.ParseKey(key); err == nil && k.Tag == leveldb.SubChunkPrefix {
+		atomic.AddInt32(&c.gets, 1)
+		time.Sleep(10 * time.Millisecond)
+	}
+	return c.LevelDB.Get(key)
+}
+
+func TestConcurrentGetBlock(t *testing.T) {
+	db := &countingLevelDB{LevelDB: mock.ValidLevelDB()}
+	w := newWorld(db)
+
+	want, err := newWorld(mock.ValidLevelDB()).GetBlock(0, 1, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			b, err := w.GetBlock(0, 1, 0, 0)
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+				return
+			}
+
+			if b.ID != want.ID {
+				t.Errorf("expected block '%s': got '%s'", want.ID, b.ID)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if db.gets != 1 {
+		t.Errorf("expected sub chunk to be read once: read %d times", db.gets)
+	}
+}
+
+func TestConcurrentSetBlock(t *testing.T) {
+	w := newWorld(mock.ValidLevelDB())
+
+	wg := sync.WaitGroup{}
+
+	for x := 0; x < 8; x++ {
+		wg.Add(2)
+
+		go func(x int) {
+			defer wg.Done()
+
+			if err := w.SetBlock(x, 0, 0, 0, Block{ID: "minecraft:stone"}); err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		}(x)
+
+		go func(x int) {
+			defer wg.Done()
+
+			if _, err := w.GetBlock(x, 0, 0, 0); err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		}(x)
+	}
+
+	wg.Wait()
+
+	for x := 0; x < 8; x++ {
+		b, err := w.GetBlock(x, 0, 0, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if b.ID != "minecraft:stone" {
+			t.Errorf("expected block %d to be 'minecraft:stone': got '%s'", x, b.ID)
+		}
+	}
+}
+
+// slowPutLevelDB delays calls to Put, so concurrent writers overlap while the cache is evicting.
+type slowPutLevelDB struct {
+	*mock.LevelDB
+}
+
+func (s *slowPutLevelDB) Put(key, value []byte) error {
+	time.Sleep(2 * time.Millisecond)
+	return s.LevelDB.Put(key, value)
+}
+
+func TestConcurrentSetBlockEvicting(t *testing.T) {
+	db := mock.ValidLevelDB()
+
+	w := newWorld(&slowPutLevelDB{db})
+	w.SetCacheConfig(CacheConfig{MaxEntries: 1})
+
+	const writers, writes = 8, 16
+
+	wg := sync.WaitGroup{}
+
+	// Writers alternate between two sub chunks, so each evicts the other while it is being written
+	for g := 0; g < writers; g++ {
+		wg.Add(1)
+
+		go func(g int) {
+			defer wg.Done()
+
+			for i := 0; i < writes; i++ {
+				if err := w.SetBlock(g, (i%2)*16, i, 0, Block{ID: "minecraft:stone"}); err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			}
+		}(g)
+	}
+
+	wg.Wait()
+
+	if s := w.CacheStats(); s.Evictions == 0 {
+		t.Fatalf("expected sub chunks to be evicted")
+	}
+
+	// Read the written sub chunks back from the database rather than the cache
+	w = newWorld(db)
+	lost := 0
+
+	for g := 0; g < writers; g++ {
+		for i := 0; i < writes; i++ {
+			b, err := w.GetBlock(g, (i%2)*16, i, 0)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if b.ID != "minecraft:stone" {
+				lost++
+			}
+		}
+	}
+
+	if lost > 0 {
+		t.Errorf("%d of %d writes were lost", lost, writers*writes)
+	}
+}
+
+func TestConcurrentReplaceBlocks(t *testing.T) {
+	w := scanTestWorld()
+
+	isFence := func(b Block) bool { return b.ID == "minecraft:fence" }
+	stone := Block{ID: "minecraft:stone"}
+
+	// A pinned sub chunk must be changed by the replacement rather than hiding it
+	_, release, err := w.subChunk(0, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	wg := sync.WaitGroup{}
+
+	for x := 0; x < 8; x++ {
+		wg.Add(1)
+
+		go func(x int) {
+			defer wg.Done()
+
+			if err := w.SetBlock(x, 15, 15, 0, stone); err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		}(x)
+	}
+
+	if _, err := w.ReplaceBlocks(context.Background(), 0, ScanConfig{}, isFence, Block{ID: "minecraft:crimson_fence"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	wg.Wait()
+
+	b, err := w.GetBlock(0, 1, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if b.ID != "minecraft:crimson_fence" {
+		t.Errorf("expected replaced block to be 'minecraft:crimson_fence': got '%s'", b.ID)
+	}
+
+	release()
+
+	// Read the sub chunk back from the database rather than the cache
+	w = newWorld(w.db)
+
+	for x := 0; x < 8; x++ {
+		b, err := w.GetBlock(x, 15, 15, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if b.ID != stone.ID {
+			t.Errorf("expected block %d to be '%s': got '%s'", x, stone.ID, b.ID)
+		}
+	}
+
+	err = w.ScanBlocks(context.Background(), 0, ScanConfig{}, func(b Block) error {
+		if isFence(b) {
+			t.Errorf("block %d %d %d was not replaced", b.X, b.Y, b.Z)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestConcurrentGetBlockEntity(t *testing.T) {
+	w, _ := blockEntityTestWorld()
+
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+
+			if e, err := w.GetBlockEntity(4, 5, 6, 0); err != nil || e == nil {
+				t.Errorf("expected sign block entity: got %v, %v", e, err)
+			}
+		}()
+
+		go func(i int) {
+			defer wg.Done()
+
+			if err := w.SetBlockEntity(i, 10, 0, 0, testBlockEntity("Chest", 0, 0, 0)); err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	for i := 0; i < 8; i++ {
+		if e, err := w.GetBlockEntity(i, 10, 0, 0); err != nil || e == nil {
+			t.Errorf("expected chest block entity %d: got %v, %v", i, e, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package world
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/danhale-git/mine/leveldb"
+	goleveldb "github.com/midnightfreddie/goleveldb/leveldb"
+	"github.com/midnightfreddie/goleveldb/leveldb/util"
+)
+
+// levelDB is the leveldb database in a world directory, read with the goleveldb fork which supports the zlib
+// compression used by Minecraft.
+type levelDB struct {
+	db *goleveldb.DB
+}
+
+// openLevelDB opens the database in the 'db' directory of the given world directory.
+func openLevelDB(worldPath string) (*levelDB, error) {
+	dbPath := filepath.Join(worldPath, "db")
+
+	info, err := os.Stat(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dbPath)
+	}
+
+	db, err := goleveldb.OpenFile(dbPath, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &levelDB{db: db}, nil
+}
+
+// Get returns a copy of the value with the given key, as goleveldb values must not be modified. It returns
+// leveldb.ErrNotFound if there is no value.
+func (l *levelDB) Get(key []byte) ([]byte, error) {
+	value, err := l.db.Get(key, nil)
+	if errors.Is(err, goleveldb.ErrNotFound) {
+		return nil, leveldb.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte{}, value...), nil
+}
+
+func (l *levelDB) Put(key, value []byte) error {
+	return l.db.Put(key, value, nil)
+}
+
+func (l *levelDB) Delete(key []byte) error {
+	return l.db.Delete(key, nil)
+}
+
+// Iterate calls fn with each key with the given prefix, in key order, from a snapshot of the database taken when it is
+// called.
+func (l *levelDB) Iterate(prefix []byte, fn func(key []byte) error) error {
+	var r *util.Range
+	if len(prefix) > 0 {
+		r = util.BytesPrefix(prefix)
+	}
+
+	iter := l.db.NewIterator(r, nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		if err := fn(iter.Key()); err != nil {
+			return err
+		}
+	}
+
+	return iter.Error()
+}
+
+func (l *levelDB) Close() error {
+	return l.db.Close()
+}
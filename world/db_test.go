@@ -0,0 +1,69 @@
+package world
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/danhale-git/mine/leveldb"
+)
+
+func TestLevelDBIterate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "db"), 0755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	db, err := openLevelDB(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer db.Close()
+
+	for _, k := range []string{"digp2", "actorprefix1", "digp1", "map_1"} {
+		if err := db.Put([]byte(k), []byte{1}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	keys := func(prefix string) []string {
+		got := make([]string, 0)
+		err := db.Iterate([]byte(prefix), func(key []byte) error {
+			got = append(got, string(key))
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		return got
+	}
+
+	if got, want := keys("digp"), []string{"digp1", "digp2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected keys %v: got %v", want, got)
+	}
+
+	if got := keys(""); len(got) != 4 || got[0] != "actorprefix1" {
+		t.Errorf("expected every key in order: got %v", got)
+	}
+
+	stop := errors.New("stop")
+	n := 0
+
+	err = db.Iterate(nil, func(key []byte) error {
+		n++
+		return stop
+	})
+	if err != stop || n != 1 {
+		t.Errorf("expected iteration to stop with the callback's error: got %v after %d keys", err, n)
+	}
+
+	if _, err := db.Get([]byte("digp3")); !errors.Is(err, leveldb.ErrNotFound) {
+		t.Errorf("expected leveldb.ErrNotFound: got %v", err)
+	}
+
+	if _, err := openLevelDB(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("expected error opening world with no db directory")
+	}
+}
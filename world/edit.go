@@ -0,0 +1,193 @@
+package world
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/danhale-git/mine/leveldb"
+)
+
+// Fill sets every block in the box between the given corners, inclusive, to the given block and returns the number of
+// blocks set. The corners may be given in any order. Liquids are set as described by SetBlock.
+//
+// Each affected sub chunk is changed once and written back to the database. Sub chunks which are not saved are created
+// if their chunk is saved, otherwise their blocks are skipped.
+func (w *World) Fill(a, b Position, dimension int, block Block) (int, error) {
+	return w.editBox(NewBox(a, b), dimension, func(sc *subChunkData, o Position, part Box) (int, error) {
+		p, err := sc.blockPaletteIndices(block)
+		if err != nil {
+			return 0, err
+		}
+
+		n := 0
+
+		for x := part.Min.X; x <= part.Max.X; x++ {
+			for z := part.Min.Z; z <= part.Max.Z; z++ {
+				for y := part.Min.Y; y <= part.Max.Y; y++ {
+					voxelIndex, err := subChunkVoxelToIndex(x-o.X, y-o.Y, z-o.Z)
+					if err != nil {
+						return 0, err
+					}
+
+					sc.setBlockIndices(voxelIndex, p)
+					n++
+				}
+			}
+		}
+
+		return n, nil
+	})
+}
+
+// Clone copies the blocks in the box between the given corners, inclusive, to the box with its lowest corner at dest and
+// returns the number of blocks copied. The corners may be given in any order. The destination may be in a different
+// dimension and may overlap the source. Blocks in source sub chunks which are not saved are not copied.
+//
+// Destination sub chunks are changed as described by Fill.
+func (w *World) Clone(a, b Position, dimension int, dest Position, destDimension int) (int, error) {
+	src, err := w.GetRegion(a, b, dimension)
+	if err != nil {
+		return 0, fmt.Errorf("reading source region: %w", err)
+	}
+
+	sx, sy, sz := src.Size()
+	box := Box{dest, Position{dest.X + sx - 1, dest.Y + sy - 1, dest.Z + sz - 1}}
+
+	// Offset from destination to source coordinates
+	dx, dy, dz := src.Min.X-dest.X, src.Min.Y-dest.Y, src.Min.Z-dest.Z
+
+	return w.editBox(box, destDimension, func(sc *subChunkData, o Position, part Box) (int, error) {
+		// Sub chunk palette indices for each region palette index, so each block is only added once
+		local := make(map[int][2]int)
+		n := 0
+
+		for x := part.Min.X; x <= part.Max.X; x++ {
+			for z := part.Min.Z; z <= part.Max.Z; z++ {
+				for y := part.Min.Y; y <= part.Max.Y; y++ {
+					i, _ := src.Index(x+dx, y+dy, z+dz)
+					if src.Indices[i] == NotSaved {
+						continue
+					}
+
+					// A block which needed no second layer does once another block has added one
+					p, ok := local[src.Indices[i]]
+					if !ok || (p[1] < 0 && len(sc.Layers) > 1) {
+						var err error
+						if p, err = sc.blockPaletteIndices(src.Palette[src.Indices[i]]); err != nil {
+							return 0, err
+						}
+						local[src.Indices[i]] = p
+					}
+
+					voxelIndex, err := subChunkVoxelToIndex(x-o.X, y-o.Y, z-o.Z)
+					if err != nil {
+						return 0, err
+					}
+
+					sc.setBlockIndices(voxelIndex, p)
+					n++
+				}
+			}
+		}
+
+		return n, nil
+	})
+}
+
+// editBox calls edit once for each sub chunk intersecting the box, holding the sub chunk's write lock. edit is given the
+// sub chunk origin and the part of the box inside the sub chunk, in world coordinates, and returns the number of blocks
+// it changed. Changed sub chunks are compacted and written back to the database. It returns the total changed.
+func (w *World) editBox(box Box, dimension int, edit func(sc *subChunkData, o Position, part Box) (int, error)) (int, error) {
+	minSub := subChunkOrigin(box.Min.X, box.Min.Y, box.Min.Z, dimension)
+	maxSub := subChunkOrigin(box.Max.X, box.Max.Y, box.Max.Z, dimension)
+
+	total := 0
+
+	for cx := minSub.x; cx <= maxSub.x; cx++ {
+		for cz := minSub.z; cz <= maxSub.z; cz++ {
+			for cy := minSub.y; cy <= maxSub.y; cy++ {
+				n, err := w.editSubChunk(box, cx, cy, cz, dimension, edit)
+				if err != nil {
+					return total, err
+				}
+
+				total += n
+			}
+		}
+	}
+
+	return total, nil
+}
+
+// editSubChunk calls edit for the sub chunk with the given sub chunk coordinates, as described by editBox. A sub chunk
+// which is not saved is created if its chunk is saved.
+func (w *World) editSubChunk(box Box, cx, cy, cz, dimension int, edit func(sc *subChunkData, o Position, part Box) (int, error)) (int, error) {
+	o := Position{cx * chunkSize, cy * chunkSize, cz * chunkSize}
+	e := chunkSize - 1
+
+	part := Box{
+		Min: Position{max(o.X, box.Min.X), max(o.Y, box.Min.Y), max(o.Z, box.Min.Z)},
+		Max: Position{min(o.X+e, box.Max.X), min(o.Y+e, box.Max.Y), min(o.Z+e, box.Max.Z)},
+	}
+
+	sc, release, err := w.subChunk(o.X, o.Y, o.Z, dimension)
+	if errors.Is(err, &SubChunkNotSavedError{}) {
+		saved, err := w.chunkSaved(cx, cz, dimension)
+		if err != nil || !saved {
+			return 0, err
+		}
+
+		created, err := newSubChunkData(cy)
+		if err != nil {
+			return 0, err
+		}
+
+		// Concurrent edits creating the same sub chunk share one copy
+		sc, release = w.pinCreated(subChunkOrigin(o.X, o.Y, o.Z, dimension), created)
+	} else if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	n, err := edit(sc, o, part)
+	if err != nil {
+		// The cached sub chunk may be partly changed, so read it again when next used
+		w.Invalidate(o.X, o.Y, o.Z, dimension)
+		return 0, err
+	}
+
+	if n == 0 {
+		return 0, nil
+	}
+
+	for i := range sc.Layers {
+		sc.Layers[i].compact()
+	}
+
+	if err := w.putSubChunk(sc, o.X, o.Y, o.Z, dimension); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// chunkSaved reports whether the chunk with the given chunk coordinates has a version record in the world database.
+func (w *World) chunkSaved(x, z, dimension int) (bool, error) {
+	for _, tag := range []leveldb.Tag{leveldb.Version, leveldb.LegacyVersion} {
+		key := leveldb.NewChunkKey(int32(x), int32(z), int32(dimension), tag).Bytes()
+
+		_, err := w.db.Get(key)
+		if err == nil {
+			return true, nil
+		}
+
+		if !errors.Is(err, leveldb.ErrNotFound) {
+			return false, fmt.Errorf("getting chunk version with key '%x': %w", key, err)
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,143 @@
+package world
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/danhale-git/mine/leveldb"
+	"github.com/danhale-git/mine/mock"
+)
+
+func TestFill(t *testing.T) {
+	w := scanTestWorld()
+
+	planks := Block{ID: "minecraft:crimson_planks"}
+
+	// Spans two saved sub chunks and several in chunks which are not saved
+	n, err := w.Fill(Position{20, 20, 20}, Position{10, 10, 10}, 0, planks)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if want := 6 * 11 * 6; n != want {
+		t.Errorf("expected %d blocks to be set: got %d", want, n)
+	}
+
+	// Read back from the database rather than the cache
+	w.Flush()
+
+	for _, p := range []Position{{10, 10, 10}, {15, 15, 15}, {15, 16, 15}, {12, 20, 14}} {
+		b, err := w.GetBlock(p.X, p.Y, p.Z, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if b.ID != planks.ID || b.WaterLogged {
+			t.Errorf("block %d %d %d: expected %s: got %+v", p.X, p.Y, p.Z, planks.ID, b)
+		}
+	}
+
+	b, err := w.GetBlock(9, 10, 10, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if b.ID == planks.ID {
+		t.Errorf("block outside the box was set")
+	}
+
+	if _, err := w.GetBlock(16, 10, 10, 0); !errors.Is(err, &SubChunkNotSavedError{}) {
+		t.Errorf("expected sub chunk in an unsaved chunk not to be created: got error %v", err)
+	}
+}
+
+func TestFillNewSubChunk(t *testing.T) {
+	db := mock.LevelDBWithKeys(leveldb.NewSubChunkKey(0, 0, 0, 0).Bytes())
+	if err := db.Put(leveldb.NewChunkKey(0, 0, 0, leveldb.Version).Bytes(), []byte{22}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	w := newWorld(db)
+
+	n, err := w.Fill(Position{0, 32, 0}, Position{1, 32, 1}, 0, Block{ID: "minecraft:glass", WaterLogged: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if n != 4 {
+		t.Errorf("expected 4 blocks to be set: got %d", n)
+	}
+
+	b, err := w.GetBlock(1, 32, 1, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if b.ID != "minecraft:glass" || !b.WaterLogged {
+		t.Errorf("expected water logged glass: got %+v", b)
+	}
+
+	b, err = w.GetBlock(2, 33, 2, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if b.ID != airID || b.Liquid != nil {
+		t.Errorf("expected the rest of the new sub chunk to be air: got %+v", b)
+	}
+}
+
+func TestClone(t *testing.T) {
+	w := scanTestWorld()
+
+	src, err := w.GetRegion(Position{0, 0, 0}, Position{15, 3, 15}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tests := []struct {
+		dest      Position
+		dimension int
+	}{
+		{Position{80, 2, 80}, 1}, // Another dimension
+		{Position{0, 1, 0}, 0},   // Overlapping the source
+	}
+
+	for _, tt := range tests {
+		n, err := w.Clone(src.Min, src.Max, 0, tt.dest, tt.dimension)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if n != len(src.Indices) {
+			t.Errorf("expected %d blocks to be copied: got %d", len(src.Indices), n)
+		}
+
+		w.Flush()
+
+		for x := src.Min.X; x <= src.Max.X; x++ {
+			for y := src.Min.Y; y <= src.Max.Y; y++ {
+				for z := src.Min.Z; z <= src.Max.Z; z++ {
+					want, _ := src.Block(x, y, z)
+
+					dx, dy, dz := tt.dest.X+x, tt.dest.Y+y, tt.dest.Z+z
+
+					got, err := w.GetBlock(dx, dy, dz, tt.dimension)
+					if err != nil {
+						t.Fatalf("unexpected error: %s", err)
+					}
+
+					want.X, want.Y, want.Z = dx, dy, dz
+					if want.Liquid != nil {
+						want.Liquid.X, want.Liquid.Y, want.Liquid.Z = dx, dy, dz
+					}
+
+					if !reflect.DeepEqual(want, got) {
+						t.Fatalf("block %d %d %d: expected %+v: got %+v", dx, dy, dz, want, got)
+					}
+				}
+			}
+		}
+	}
+}
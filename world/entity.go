@@ -0,0 +1,424 @@
+package world
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/danhale-git/mine/leveldb"
+	"github.com/danhale-git/mine/nbt"
+)
+
+// actorIDSize is the size of an actor's unique ID as listed in a digest record.
+const actorIDSize = 8
+
+// Entity is a mob, dropped item, vehicle or other entity. Entities are stored either in a chunk's legacy Entity record
+// or, since 1.18.30, each in its own actor record listed by the chunk's digest record.
+type Entity struct {
+	Identifier string     `json:"identifier"` // e.g. 'minecraft:cow'
+	Pos        [3]float32 `json:"pos"`        // World coordinates
+	UniqueID   int64      `json:"uniqueID"`
+	CustomName string     `json:"customName,omitempty"`
+	Dimension  int        `json:"dimension"`
+
+	// ChunkX and ChunkZ are the coordinates of the chunk the entity is stored with, which is usually the chunk
+	// containing Pos.
+	ChunkX int `json:"chunkX"`
+	ChunkZ int `json:"chunkZ"`
+
+	// Legacy is true if the entity was read from a chunk's Entity record, otherwise it was read from the actor record
+	// with ActorID.
+	Legacy  bool    `json:"legacy"`
+	ActorID [8]byte `json:"actorID"`
+
+	// NBT is the entity compound holding all of the entity's data.
+	NBT nbt.NBTTag `json:"nbt"`
+}
+
+// BlockPosition returns the coordinates of the block containing the entity.
+func (e *Entity) BlockPosition() Position {
+	return Position{
+		int(math.Floor(float64(e.Pos[0]))),
+		int(math.Floor(float64(e.Pos[1]))),
+		int(math.Floor(float64(e.Pos[2]))),
+	}
+}
+
+// ChunkEntities returns the entities stored with the chunk at the given chunk coordinates, legacy entities first.
+// Actor IDs listed by the chunk's digest record which have no actor record are ignored.
+func (w *World) ChunkEntities(x, z, dimension int) ([]Entity, error) {
+	entities := make([]Entity, 0)
+
+	key := leveldb.NewChunkKey(int32(x), int32(z), int32(dimension), leveldb.Entity).Bytes()
+
+	tags, err := w.compounds(key)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range tags {
+		e := newEntity(t, x, z, dimension)
+		e.Legacy = true
+		entities = append(entities, e)
+	}
+
+	ids, err := w.actorIDs(x, z, dimension)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		key := leveldb.NewActorKey(id).Bytes()
+
+		value, err := w.get(key)
+		if err != nil {
+			return nil, fmt.Errorf("getting actor with key '%x': %w", key, err)
+		}
+
+		if value == nil {
+			continue
+		}
+
+		t, err := nbt.Read(bytes.NewReader(value))
+		if err != nil {
+			return nil, fmt.Errorf("decoding actor with key '%x': %w", key, err)
+		}
+
+		e := newEntity(t, x, z, dimension)
+		e.ActorID = id
+		entities = append(entities, e)
+	}
+
+	return entities, nil
+}
+
+// Entities returns the entities in the given dimension, ordered by chunk. If box is not nil, only entities inside the
+// box which are stored with chunks intersecting the box are returned.
+func (w *World) Entities(dimension int, box *Box) ([]Entity, error) {
+	// Chunks with entity or digest records, in the order they are first seen
+	chunks := make([]struct{ x, z int }, 0)
+	seen := make(map[struct{ x, z int }]bool)
+
+	err := w.db.Iterate(nil, func(b []byte) error {
+		k, err := leveldb.ParseKey(b)
+		if err != nil || int(k.Dimension) != dimension {
+			return nil
+		}
+
+		if !(k.Type == leveldb.DigestKey || (k.Type == leveldb.ChunkKey && k.Tag == leveldb.Entity)) {
+			return nil
+		}
+
+		c := struct{ x, z int }{int(k.X), int(k.Z)}
+
+		if box != nil && !box.containsChunk(c.x, c.z) {
+			return nil
+		}
+
+		if !seen[c] {
+			seen[c] = true
+			chunks = append(chunks, c)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("iterating keys: %w", err)
+	}
+
+	entities := make([]Entity, 0)
+
+	for _, c := range chunks {
+		found, err := w.ChunkEntities(c.x, c.z, dimension)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range found {
+			if p := e.BlockPosition(); box == nil || box.Contains(p.X, p.Y, p.Z) {
+				entities = append(entities, e)
+			}
+		}
+	}
+
+	return entities, nil
+}
+
+// actorIDs returns the actor IDs listed by the digest record of the chunk at the given chunk coordinates.
+func (w *World) actorIDs(x, z, dimension int) ([][8]byte, error) {
+	key := leveldb.NewDigestKey(int32(x), int32(z), int32(dimension)).Bytes()
+
+	value, err := w.get(key)
+	if err != nil {
+		return nil, fmt.Errorf("getting actor digest with key '%x': %w", key, err)
+	}
+
+	if len(value)%actorIDSize != 0 {
+		return nil, fmt.Errorf("actor digest with key '%x' has length %d: expected a multiple of %d",
+			key, len(value), actorIDSize)
+	}
+
+	ids := make([][8]byte, len(value)/actorIDSize)
+	for i := range ids {
+		copy(ids[i][:], value[i*actorIDSize:])
+	}
+
+	return ids, nil
+}
+
+// get returns the value stored with the given key, or nil if there is none.
+func (w *World) get(key []byte) ([]byte, error) {
+	value, err := w.db.Get(key)
+	if err != nil {
+		if errors.Is(err, leveldb.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// newEntity returns an entity with the values of the given entity compound.
+func newEntity(t nbt.NBTTag, chunkX, chunkZ, dimension int) Entity {
+	e := Entity{Dimension: dimension, ChunkX: chunkX, ChunkZ: chunkZ, NBT: t}
+
+	if v, ok := t.Child("identifier"); ok {
+		e.Identifier, _ = v.Value.(string)
+	}
+
+	if v, ok := t.Child("UniqueID"); ok {
+		e.UniqueID, _ = v.Value.(int64)
+	}
+
+	if v, ok := t.Child("CustomName"); ok {
+		e.CustomName, _ = v.Value.(string)
+	}
+
+	if v, ok := t.Child("Pos"); ok {
+		if l, ok := v.Value.(nbt.List); ok && len(l.Values) == 3 {
+			for i := range e.Pos {
+				e.Pos[i], _ = l.Values[i].(float32)
+			}
+		}
+	}
+
+	return e
+}
+
+// RemoveEntity removes the given entity, as returned by Entities or ChunkEntities. A legacy entity is removed from its
+// chunk's Entity record by matching its whole NBT compound, so e.NBT must not have been modified. Otherwise the actor
+// record is deleted and its ID removed from the chunk's digest.
+func (w *World) RemoveEntity(e Entity) error {
+	w.entityMu.Lock()
+	defer w.entityMu.Unlock()
+
+	return w.removeEntity(e)
+}
+
+// MoveEntity moves the given entity, as returned by Entities or ChunkEntities, to the given world coordinates in the
+// same dimension and returns the moved entity. The entity is stored with the chunk containing its new position, so
+// legacy entities move between Entity records and actor IDs move between digest records.
+func (w *World) MoveEntity(e Entity, pos [3]float32) (Entity, error) {
+	w.entityMu.Lock()
+	defer w.entityMu.Unlock()
+
+	tags, ok := e.NBT.Value.([]nbt.NBTTag)
+	if !ok || e.NBT.Type != nbt.TagCompound {
+		return Entity{}, fmt.Errorf("entity has NBT of type %d: expected compound", e.NBT.Type)
+	}
+
+	t := e.NBT
+	t.Value = append([]nbt.NBTTag{}, tags...)
+
+	posTag := nbt.NBTTag{Type: nbt.TagList, Name: "Pos", Value: nbt.List{
+		Type:   nbt.TagFloat,
+		Values: []interface{}{pos[0], pos[1], pos[2]},
+	}}
+
+	if err := t.SetChild(posTag); err != nil {
+		return Entity{}, err
+	}
+
+	moved := e
+	moved.Pos, moved.NBT = pos, t
+
+	b := moved.BlockPosition()
+	o := subChunkOrigin(b.X, b.Y, b.Z, e.Dimension)
+	moved.ChunkX, moved.ChunkZ = o.x, o.z
+
+	if e.Legacy {
+		src := leveldb.NewChunkKey(int32(e.ChunkX), int32(e.ChunkZ), int32(e.Dimension), leveldb.Entity).Bytes()
+
+		tags, err := w.compounds(src)
+		if err != nil {
+			return Entity{}, err
+		}
+
+		i := legacyEntityIndex(tags, e)
+		if i < 0 {
+			return Entity{}, entityNotStoredError(e)
+		}
+
+		if moved.ChunkX == e.ChunkX && moved.ChunkZ == e.ChunkZ {
+			tags[i] = t
+			if err := w.putCompounds(src, tags); err != nil {
+				return Entity{}, err
+			}
+
+			return moved, nil
+		}
+
+		// Write the entity to its new chunk first, so a failure can't lose it
+		dest := leveldb.NewChunkKey(int32(o.x), int32(o.z), int32(e.Dimension), leveldb.Entity).Bytes()
+
+		destTags, err := w.compounds(dest)
+		if err != nil {
+			return Entity{}, err
+		}
+
+		if err := w.putCompounds(dest, append(destTags, t)); err != nil {
+			return Entity{}, err
+		}
+
+		if err := w.putCompounds(src, append(tags[:i], tags[i+1:]...)); err != nil {
+			return Entity{}, err
+		}
+
+		return moved, nil
+	}
+
+	buf := bytes.Buffer{}
+	if err := nbt.Write(&buf, t); err != nil {
+		return Entity{}, fmt.Errorf("encoding actor: %w", err)
+	}
+
+	key := leveldb.NewActorKey(e.ActorID).Bytes()
+	if err := w.db.Put(key, buf.Bytes()); err != nil {
+		return Entity{}, fmt.Errorf("putting actor with key '%x': %w", key, err)
+	}
+
+	if moved.ChunkX == e.ChunkX && moved.ChunkZ == e.ChunkZ {
+		return moved, nil
+	}
+
+	// Add the ID to the new digest first, so a failure can't leave the actor record unlisted
+	if err := w.editActorIDs(moved.ChunkX, moved.ChunkZ, e.Dimension, func(ids [][8]byte) [][8]byte {
+		return append(removeActorID(ids, e.ActorID), e.ActorID)
+	}); err != nil {
+		return Entity{}, err
+	}
+
+	if err := w.editActorIDs(e.ChunkX, e.ChunkZ, e.Dimension, func(ids [][8]byte) [][8]byte {
+		return removeActorID(ids, e.ActorID)
+	}); err != nil {
+		return Entity{}, err
+	}
+
+	return moved, nil
+}
+
+// removeEntity removes the given entity as described by RemoveEntity. The caller must hold entityMu.
+func (w *World) removeEntity(e Entity) error {
+	if e.Legacy {
+		key := leveldb.NewChunkKey(int32(e.ChunkX), int32(e.ChunkZ), int32(e.Dimension), leveldb.Entity).Bytes()
+
+		tags, err := w.compounds(key)
+		if err != nil {
+			return err
+		}
+
+		i := legacyEntityIndex(tags, e)
+		if i < 0 {
+			return entityNotStoredError(e)
+		}
+
+		return w.putCompounds(key, append(tags[:i], tags[i+1:]...))
+	}
+
+	key := leveldb.NewActorKey(e.ActorID).Bytes()
+	if err := w.db.Delete(key); err != nil {
+		return fmt.Errorf("deleting actor with key '%x': %w", key, err)
+	}
+
+	return w.editActorIDs(e.ChunkX, e.ChunkZ, e.Dimension, func(ids [][8]byte) [][8]byte {
+		return removeActorID(ids, e.ActorID)
+	})
+}
+
+// legacyEntityIndex returns the index of the given legacy entity in the tags of its chunk's Entity record, or -1 if it
+// is not there. The whole compound is matched, as UniqueID may be missing from legacy entities.
+func legacyEntityIndex(tags []nbt.NBTTag, e Entity) int {
+	for i := range tags {
+		if reflect.DeepEqual(tags[i], e.NBT) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func entityNotStoredError(e Entity) error {
+	return fmt.Errorf("entity '%s' %d is not stored in chunk %d %d", e.Identifier, e.UniqueID, e.ChunkX, e.ChunkZ)
+}
+
+// compounds returns the root compound tags stored with the given key, or an empty slice if there is no record.
+func (w *World) compounds(key []byte) ([]nbt.NBTTag, error) {
+	value, err := w.get(key)
+	if err != nil {
+		return nil, fmt.Errorf("getting record with key '%x': %w", key, err)
+	}
+
+	tags, err := parseCompounds(value)
+	if err != nil {
+		return nil, fmt.Errorf("decoding record with key '%x': %w", key, err)
+	}
+
+	return tags, nil
+}
+
+// editActorIDs reads the actor IDs listed by the digest record of the chunk at the given chunk coordinates and writes
+// back the IDs returned by edit, or deletes the record if there are none.
+func (w *World) editActorIDs(x, z, dimension int, edit func(ids [][8]byte) [][8]byte) error {
+	ids, err := w.actorIDs(x, z, dimension)
+	if err != nil {
+		return err
+	}
+
+	ids = edit(ids)
+
+	key := leveldb.NewDigestKey(int32(x), int32(z), int32(dimension)).Bytes()
+
+	if len(ids) == 0 {
+		if err := w.db.Delete(key); err != nil {
+			return fmt.Errorf("deleting actor digest with key '%x': %w", key, err)
+		}
+		return nil
+	}
+
+	value := make([]byte, 0, len(ids)*actorIDSize)
+	for _, id := range ids {
+		value = append(value, id[:]...)
+	}
+
+	if err := w.db.Put(key, value); err != nil {
+		return fmt.Errorf("putting actor digest with key '%x': %w", key, err)
+	}
+
+	return nil
+}
+
+// removeActorID returns ids without any occurrence of id.
+func removeActorID(ids [][8]byte, id [8]byte) [][8]byte {
+	kept := make([][8]byte, 0, len(ids))
+
+	for _, i := range ids {
+		if i != id {
+			kept = append(kept, i)
+		}
+	}
+
+	return kept
+}
@@ -0,0 +1,293 @@
+package world
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/danhale-git/mine/leveldb"
+	"github.com/danhale-git/mine/mock"
+	"github.com/danhale-git/mine/nbt"
+)
+
+func testEntity(id string, uniqueID int64, name string, x, y, z float32) nbt.NBTTag {
+	return nbt.NBTTag{Type: nbt.TagCompound, Value: []nbt.NBTTag{
+		{Type: nbt.TagString, Name: "identifier", Value: id},
+		{Type: nbt.TagLong, Name: "UniqueID", Value: uniqueID},
+		{Type: nbt.TagString, Name: "CustomName", Value: name},
+		{Type: nbt.TagList, Name: "Pos", Value: nbt.List{Type: nbt.TagFloat, Values: []interface{}{x, y, z}}},
+	}}
+}
+
+// entityTestWorld returns a world with a legacy entity record in chunk 0 0 and two actors in chunk 1 0, one of which
+// has no actor record.
+func entityTestWorld() (*World, *mock.LevelDB) {
+	cat := [8]byte{0, 0, 0, 1, 0, 0, 0, 3}
+	missing := [8]byte{0, 0, 0, 1, 0, 0, 0, 4}
+
+	db := mock.LevelDBWithRecords(
+		mock.Record{Key: leveldb.NewChunkKey(0, 0, 0, leveldb.Entity).Bytes(), NBT: []nbt.NBTTag{
+			testEntity("minecraft:cow", 1, "", 1.5, 4, 2.5),
+			testEntity("minecraft:wolf", 2, "Rex", 3.5, 4, 15.9),
+		}},
+		mock.Record{Key: leveldb.NewActorKey(cat).Bytes(), NBT: []nbt.NBTTag{
+			testEntity("minecraft:cat", 3, "Tom", 20, 70, -0.5),
+		}},
+		mock.Record{Key: leveldb.NewDigestKey(1, 0, 0).Bytes(), Value: append(cat[:], missing[:]...)},
+	)
+
+	return newWorld(db), db
+}
+
+func TestChunkEntities(t *testing.T) {
+	w, _ := entityTestWorld()
+
+	entities, err := w.ChunkEntities(0, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(entities) != 2 {
+		t.Fatalf("expected 2 entities: got %d", len(entities))
+	}
+
+	wolf := entities[1]
+	if wolf.Identifier != "minecraft:wolf" || wolf.CustomName != "Rex" || wolf.UniqueID != 2 || !wolf.Legacy {
+		t.Errorf("unexpected entity %+v", wolf)
+	}
+
+	if p := wolf.BlockPosition(); p != (Position{3, 4, 15}) {
+		t.Errorf("unexpected block position %+v", p)
+	}
+
+	entities, err = w.ChunkEntities(1, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(entities) != 1 {
+		t.Fatalf("expected 1 entity: got %d", len(entities))
+	}
+
+	cat := entities[0]
+	if cat.Identifier != "minecraft:cat" || cat.Legacy || cat.ActorID != [8]byte{0, 0, 0, 1, 0, 0, 0, 3} {
+		t.Errorf("unexpected entity %+v", cat)
+	}
+
+	if p := cat.BlockPosition(); p != (Position{20, 70, -1}) {
+		t.Errorf("unexpected block position %+v", p)
+	}
+}
+
+func TestEntities(t *testing.T) {
+	w, db := entityTestWorld()
+
+	all, err := w.Entities(0, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(all) != 3 {
+		t.Errorf("expected 3 entities: got %d", len(all))
+	}
+
+	box := NewBox(Position{0, 0, 0}, Position{31, 255, 10})
+
+	inBox, err := w.Entities(0, &box)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(inBox) != 1 || inBox[0].Identifier != "minecraft:cow" {
+		t.Errorf("expected only the cow to be in the box: got %+v", inBox)
+	}
+
+	if none, err := w.Entities(1, nil); err != nil || len(none) != 0 {
+		t.Errorf("expected no entities in another dimension: got %d, %v", len(none), err)
+	}
+
+	if err := db.Put(leveldb.NewDigestKey(1, 0, 0).Bytes(), []byte{1, 2, 3}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := w.ChunkEntities(1, 0, 0); err == nil {
+		t.Errorf("expected error reading invalid digest")
+	}
+}
+
+func TestRemoveEntity(t *testing.T) {
+	w, db := entityTestWorld()
+
+	all, err := w.Entities(0, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, e := range all {
+		if e.Identifier == "minecraft:wolf" {
+			continue
+		}
+
+		if err := w.RemoveEntity(e); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	left, err := w.Entities(0, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(left) != 1 || left[0].Identifier != "minecraft:wolf" {
+		t.Errorf("expected only the wolf to be left: got %+v", left)
+	}
+
+	if _, err := db.Get(leveldb.NewActorKey([8]byte{0, 0, 0, 1, 0, 0, 0, 3}).Bytes()); err == nil {
+		t.Errorf("expected actor record to be deleted")
+	}
+
+	ids, err := w.actorIDs(1, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(ids) != 1 || ids[0] != [8]byte{0, 0, 0, 1, 0, 0, 0, 4} {
+		t.Errorf("expected only the actor without a record to be left in the digest: got %v", ids)
+	}
+
+	if err := w.RemoveEntity(all[0]); err == nil {
+		t.Errorf("expected error removing entity twice")
+	}
+}
+
+func TestRemoveEntityWithoutUniqueID(t *testing.T) {
+	noID := func(x float32) nbt.NBTTag {
+		return nbt.NBTTag{Type: nbt.TagCompound, Value: []nbt.NBTTag{
+			{Type: nbt.TagString, Name: "identifier", Value: "minecraft:armor_stand"},
+			{Type: nbt.TagList, Name: "Pos", Value: nbt.List{Type: nbt.TagFloat, Values: []interface{}{x, float32(4), float32(8)}}},
+		}}
+	}
+
+	w := newWorld(mock.LevelDBWithRecords(mock.Record{
+		Key: leveldb.NewChunkKey(0, 1, 0, leveldb.Entity).Bytes(),
+		NBT: []nbt.NBTTag{noID(1), noID(2), noID(2)},
+	}))
+
+	all, err := w.ChunkEntities(0, 1, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := w.RemoveEntity(all[1]); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	left, err := w.ChunkEntities(0, 1, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(left) != 2 || left[0].Pos[0] != 1 || left[1].Pos[0] != 2 {
+		t.Errorf("expected only one entity to be removed: got %+v", left)
+	}
+}
+
+func TestMoveEntity(t *testing.T) {
+	w, _ := entityTestWorld()
+
+	all, err := w.Entities(0, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, e := range all {
+		moved, err := w.MoveEntity(e, [3]float32{-20.5, 64, 40})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if moved.ChunkX != -2 || moved.ChunkZ != 2 {
+			t.Errorf("unexpected chunk %d %d", moved.ChunkX, moved.ChunkZ)
+		}
+	}
+
+	for _, c := range []struct{ x, z int }{{0, 0}, {1, 0}} {
+		left, err := w.ChunkEntities(c.x, c.z, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if len(left) != 0 {
+			t.Errorf("expected no entities left in chunk %d %d: got %d", c.x, c.z, len(left))
+		}
+	}
+
+	moved, err := w.ChunkEntities(-2, 2, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(moved) != len(all) {
+		t.Fatalf("expected %d entities in the new chunk: got %d", len(all), len(moved))
+	}
+
+	for _, e := range moved {
+		if e.Pos != [3]float32{-20.5, 64, 40} {
+			t.Errorf("unexpected position %v of %s", e.Pos, e.Identifier)
+		}
+	}
+}
+
+// failPutLevelDB fails to put values with the given key.
+type failPutLevelDB struct {
+	*mock.LevelDB
+	key []byte
+}
+
+func (f *failPutLevelDB) Put(key, value []byte) error {
+	if bytes.Equal(key, f.key) {
+		return errors.New("put failed")
+	}
+	return f.LevelDB.Put(key, value)
+}
+
+func TestMoveEntityErrors(t *testing.T) {
+	w, db := entityTestWorld()
+
+	if _, err := w.MoveEntity(Entity{Legacy: true}, [3]float32{}); err == nil {
+		t.Errorf("expected error moving entity with no compound")
+	}
+
+	all, err := w.Entities(0, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Writes to chunk -2 2 fail, so nothing is moved out of the source chunks
+	w = newWorld(&failPutLevelDB{db, leveldb.NewChunkKey(-2, 2, 0, leveldb.Entity).Bytes()})
+	for _, e := range all {
+		if e.Legacy {
+			if _, err := w.MoveEntity(e, [3]float32{-20.5, 64, 40}); err == nil {
+				t.Errorf("expected error moving %s", e.Identifier)
+			}
+		}
+	}
+
+	w = newWorld(&failPutLevelDB{db, leveldb.NewDigestKey(-2, 2, 0).Bytes()})
+	for _, e := range all {
+		if !e.Legacy {
+			if _, err := w.MoveEntity(e, [3]float32{-20.5, 64, 40}); err == nil {
+				t.Errorf("expected error moving %s", e.Identifier)
+			}
+		}
+	}
+
+	left, err := w.Entities(0, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(left) != len(all) {
+		t.Errorf("expected %d entities after failed moves: got %d", len(all), len(left))
+	}
+}
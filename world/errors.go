@@ -0,0 +1,69 @@
+package world
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// UnsupportedVersionError is returned if a sub chunk has a format version which can't be parsed.
+type UnsupportedVersionError struct {
+	Key     []byte // The key of the sub chunk, if known
+	Version int8
+}
+
+func (e *UnsupportedVersionError) Error() string {
+	return fmt.Sprintf("sub chunk with key '%x' has unsupported version %d", e.Key, e.Version)
+}
+
+// CorruptStorageError is returned if a sub chunk's block storage can't be parsed.
+type CorruptStorageError struct {
+	Key    []byte // The key of the sub chunk, if known
+	Offset int64  // The offset into the sub chunk value at which parsing failed
+	Err    error
+}
+
+func (e *CorruptStorageError) Error() string {
+	return fmt.Sprintf("sub chunk with key '%x' is corrupt at offset %d: %s", e.Key, e.Offset, e.Err)
+}
+
+func (e *CorruptStorageError) Unwrap() error {
+	return e.Err
+}
+
+// corruptStorage returns a *CorruptStorageError for err at the current position of r.
+func corruptStorage(r *bytes.Reader, err error) *CorruptStorageError {
+	return &CorruptStorageError{
+		Offset: r.Size() - int64(r.Len()),
+		Err:    err,
+	}
+}
+
+// ChunkDataNotSavedError is returned if a chunk has no Data3D or Data2D record holding its biomes and height map.
+type ChunkDataNotSavedError struct {
+	X, Z      int // Chunk coordinates
+	Dimension int
+}
+
+func (e *ChunkDataNotSavedError) Error() string {
+	return fmt.Sprintf("chunk %d %d in dimension %d has no biome or height map data", e.X, e.Z, e.Dimension)
+}
+
+// Is implements Is(error) to support errors.Is()
+func (e *ChunkDataNotSavedError) Is(tgt error) bool {
+	_, ok := tgt.(*ChunkDataNotSavedError)
+	return ok
+}
+
+// CorruptBlockEntitiesError is returned if a chunk's BlockEntity record can't be parsed.
+type CorruptBlockEntitiesError struct {
+	Key []byte // The key of the BlockEntity record
+	Err error
+}
+
+func (e *CorruptBlockEntitiesError) Error() string {
+	return fmt.Sprintf("block entities with key '%x' are corrupt: %s", e.Key, e.Err)
+}
+
+func (e *CorruptBlockEntitiesError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,387 @@
+package world
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/danhale-git/mine/nbt"
+)
+
+// levelDatName is the name of the file in a world directory holding the world settings.
+const levelDatName = "level.dat"
+
+// levelDatBackupName is the name of the copy of the previous level.dat, which Minecraft also keeps.
+const levelDatBackupName = "level.dat_old"
+
+// levelDatHeaderSize is the size of the level.dat header, which is the storage version and the length of the NBT data
+// as little-endian int32s.
+const levelDatHeaderSize = 8
+
+// knownGameRules are the names of the game rules in current versions of Minecraft, in name order. They are only used to
+// describe rules, as the rules of a world are read from its level.dat.
+var knownGameRules = []string{
+	"commandblockoutput", "commandblocksenabled", "dodaylightcycle", "doentitydrops", "dofiretick",
+	"doimmediaterespawn", "doinsomnia", "domobloot", "domobspawning", "dotiledrops", "doweathercycle", "drowningdamage",
+	"falldamage", "firedamage", "freezedamage", "functioncommandlimit", "keepinventory", "maxcommandchainlength",
+	"mobgriefing", "naturalregeneration", "playerssleepingpercentage", "pvp", "randomtickspeed", "recipesunlock",
+	"respawnblocksexplode", "sendcommandfeedback", "showbordereffect", "showcoordinates", "showdeathmessages",
+	"showtags", "spawnradius", "tntexplodes",
+}
+
+// Difficulty is the difficulty of a world.
+type Difficulty int32
+
+const (
+	Peaceful Difficulty = iota
+	Easy
+	Normal
+	Hard
+)
+
+func (d Difficulty) String() string {
+	switch d {
+	case Peaceful:
+		return "peaceful"
+	case Easy:
+		return "easy"
+	case Normal:
+		return "normal"
+	case Hard:
+		return "hard"
+	}
+
+	return fmt.Sprintf("unknown (%d)", int32(d))
+}
+
+// LevelData is the contents of a world's level.dat file. Settings are read and changed through typed methods, and all
+// other tags are kept as they were read when it is written.
+type LevelData struct {
+	// StorageVersion is the version from the file header.
+	StorageVersion int32
+
+	// Root is the root compound tag. Tags in it may be read or changed directly.
+	Root nbt.NBTTag
+}
+
+// ReadLevelData reads the level.dat file in the given world directory.
+func ReadLevelData(worldPath string) (*LevelData, error) {
+	f, err := os.Open(filepath.Join(worldPath, levelDatName))
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", levelDatName, err)
+	}
+	defer f.Close()
+
+	l, err := DecodeLevelData(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", levelDatName, err)
+	}
+
+	return l, nil
+}
+
+// DecodeLevelData reads level data in the level.dat format from r.
+func DecodeLevelData(r io.Reader) (*LevelData, error) {
+	l := &LevelData{}
+
+	var length int32
+	if err := readLittleEndian(r, &l.StorageVersion); err != nil {
+		return nil, fmt.Errorf("reading storage version: %w", err)
+	}
+
+	if err := readLittleEndian(r, &length); err != nil {
+		return nil, fmt.Errorf("reading data length: %w", err)
+	}
+
+	if length < 0 {
+		return nil, fmt.Errorf("invalid data length %d", length)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("reading %d bytes of level data: %w", length, err)
+	}
+
+	var err error
+	if l.Root, err = nbt.Read(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("reading level data: %w", err)
+	}
+
+	if l.Root.Type != nbt.TagCompound {
+		return nil, fmt.Errorf("level data root has type %d: expected compound", l.Root.Type)
+	}
+
+	return l, nil
+}
+
+// Encode writes the level data to w in the level.dat format.
+func (l *LevelData) Encode(w io.Writer) error {
+	data := bytes.Buffer{}
+	if err := nbt.Write(&data, l.Root); err != nil {
+		return fmt.Errorf("encoding level data: %w", err)
+	}
+
+	if err := writeLittleEndian(w, l.StorageVersion); err != nil {
+		return fmt.Errorf("writing storage version: %w", err)
+	}
+
+	if err := writeLittleEndian(w, int32(data.Len())); err != nil {
+		return fmt.Errorf("writing data length: %w", err)
+	}
+
+	if _, err := w.Write(data.Bytes()); err != nil {
+		return fmt.Errorf("writing level data: %w", err)
+	}
+
+	return nil
+}
+
+// Write writes the level data to the level.dat file in the given world directory. The existing file is copied to
+// level.dat_old first, and the new file is written to a temporary file which then replaces level.dat, so level.dat is
+// never partly written.
+func (l *LevelData) Write(worldPath string) error {
+	buf := bytes.Buffer{}
+	if err := l.Encode(&buf); err != nil {
+		return err
+	}
+
+	path := filepath.Join(worldPath, levelDatName)
+
+	old, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("reading %s: %w", levelDatName, err)
+	}
+
+	if err == nil {
+		if err := os.WriteFile(filepath.Join(worldPath, levelDatBackupName), old, 0644); err != nil {
+			return fmt.Errorf("writing %s: %w", levelDatBackupName, err)
+		}
+	}
+
+	tmp := path + ".tmp"
+
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", tmp, err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("replacing %s: %w", levelDatName, err)
+	}
+
+	return nil
+}
+
+// LevelName returns the world's display name.
+func (l *LevelData) LevelName() string {
+	v, _ := l.value("LevelName").(string)
+	return v
+}
+
+// GameMode returns the world's default game mode.
+func (l *LevelData) GameMode() GameMode {
+	v, _ := l.value("GameType").(int32)
+	return GameMode(v)
+}
+
+// SetGameMode sets the world's default game mode.
+func (l *LevelData) SetGameMode(g GameMode) error {
+	return l.set("GameType", nbt.TagInt, int32(g))
+}
+
+// LastPlayed returns the time the world was last played.
+func (l *LevelData) LastPlayed() time.Time {
+	v, _ := l.value("LastPlayed").(int64)
+	return time.Unix(v, 0)
+}
+
+// SpawnPoint returns the world spawn point.
+func (l *LevelData) SpawnPoint() Position {
+	x, _ := l.value("SpawnX").(int32)
+	y, _ := l.value("SpawnY").(int32)
+	z, _ := l.value("SpawnZ").(int32)
+
+	return Position{int(x), int(y), int(z)}
+}
+
+// SetSpawnPoint sets the world spawn point.
+func (l *LevelData) SetSpawnPoint(p Position) error {
+	if err := l.set("SpawnX", nbt.TagInt, int32(p.X)); err != nil {
+		return err
+	}
+
+	if err := l.set("SpawnY", nbt.TagInt, int32(p.Y)); err != nil {
+		return err
+	}
+
+	return l.set("SpawnZ", nbt.TagInt, int32(p.Z))
+}
+
+// Seed returns the world generation seed.
+func (l *LevelData) Seed() int64 {
+	v, _ := l.value("RandomSeed").(int64)
+	return v
+}
+
+// Time returns the world time in ticks.
+func (l *LevelData) Time() int64 {
+	v, _ := l.value("Time").(int64)
+	return v
+}
+
+// SetTime sets the world time in ticks.
+func (l *LevelData) SetTime(t int64) error {
+	return l.set("Time", nbt.TagLong, t)
+}
+
+// Difficulty returns the world difficulty.
+func (l *LevelData) Difficulty() Difficulty {
+	v, _ := l.value("Difficulty").(int32)
+	return Difficulty(v)
+}
+
+// SetDifficulty sets the world difficulty.
+func (l *LevelData) SetDifficulty(d Difficulty) error {
+	if d < Peaceful || d > Hard {
+		return fmt.Errorf("invalid difficulty %d", int32(d))
+	}
+
+	return l.set("Difficulty", nbt.TagInt, int32(d))
+}
+
+// GameRules returns the value of each game rule stored in the level data, keyed by name. Values are bool or int.
+//
+// Game rules are the byte and int tags of the root compound with lower case names, so rules added by newer versions of
+// Minecraft are included. Other settings have mixed case names.
+func (l *LevelData) GameRules() map[string]interface{} {
+	rules := make(map[string]interface{})
+
+	tags, _ := l.Root.Value.([]nbt.NBTTag)
+	for i := range tags {
+		if v, ok := gameRuleValue(&tags[i]); ok {
+			rules[tags[i].Name] = v
+		}
+	}
+
+	return rules
+}
+
+// GameRule returns the value of the named game rule, which is bool for byte rules or int for int rules. The name is not
+// case sensitive. The boolean is false if the level data has no such game rule.
+func (l *LevelData) GameRule(name string) (interface{}, bool) {
+	t, ok := l.Root.Child(strings.ToLower(name))
+	if !ok {
+		return nil, false
+	}
+
+	return gameRuleValue(t)
+}
+
+// KnownGameRule reports whether name is a game rule in current versions of Minecraft. A world's level.dat may not have
+// every known rule, and may have rules which are not known.
+func KnownGameRule(name string) bool {
+	name = strings.ToLower(name)
+	i := sort.SearchStrings(knownGameRules, name)
+	return i < len(knownGameRules) && knownGameRules[i] == name
+}
+
+// SetGameRule sets the value of the named game rule, which must be bool for byte rules or int for int rules. The name is
+// not case sensitive. It returns an error if the level data has no such game rule or the value has the wrong type.
+func (l *LevelData) SetGameRule(name string, value interface{}) error {
+	name = strings.ToLower(name)
+
+	old, ok := l.GameRule(name)
+	if !ok {
+		return fmt.Errorf("unknown game rule '%s'", name)
+	}
+
+	switch v := value.(type) {
+	case bool:
+		if _, ok := old.(bool); ok {
+			var b int8
+			if v {
+				b = 1
+			}
+			return l.set(name, nbt.TagByte, b)
+		}
+	case int:
+		if _, ok := old.(int); ok {
+			return l.set(name, nbt.TagInt, int32(v))
+		}
+	}
+
+	return fmt.Errorf("game rule '%s' has a %T value: got %T", name, old, value)
+}
+
+// Experiments returns whether each experiment stored in the level data is enabled, keyed by name.
+func (l *LevelData) Experiments() map[string]bool {
+	experiments := make(map[string]bool)
+
+	if t, ok := l.Root.Child("experiments"); ok {
+		tags, _ := t.Value.([]nbt.NBTTag)
+		for _, e := range tags {
+			if v, ok := e.Value.(int8); ok {
+				experiments[e.Name] = v != 0
+			}
+		}
+	}
+
+	return experiments
+}
+
+// SetExperiment enables or disables the named experiment, adding it if needed.
+func (l *LevelData) SetExperiment(name string, enabled bool) error {
+	t, ok := l.Root.Child("experiments")
+	if !ok {
+		if err := l.Root.SetChild(nbt.NBTTag{Type: nbt.TagCompound, Name: "experiments", Value: []nbt.NBTTag{}}); err != nil {
+			return err
+		}
+		t, _ = l.Root.Child("experiments")
+	}
+
+	var v int8
+	if enabled {
+		v = 1
+	}
+
+	return t.SetChild(nbt.NBTTag{Type: nbt.TagByte, Name: name, Value: v})
+}
+
+// value returns the value of the named tag in the root compound, or nil if there is no such tag.
+func (l *LevelData) value(name string) interface{} {
+	if t, ok := l.Root.Child(name); ok {
+		return t.Value
+	}
+
+	return nil
+}
+
+// set sets the named tag in the root compound, adding it if needed. It returns an error if the tag exists with a
+// different type.
+func (l *LevelData) set(name string, tagType byte, value interface{}) error {
+	if t, ok := l.Root.Child(name); ok && t.Type != tagType {
+		return fmt.Errorf("tag '%s' has type %d: expected %d", name, t.Type, tagType)
+	}
+
+	return l.Root.SetChild(nbt.NBTTag{Type: tagType, Name: name, Value: value})
+}
+
+// gameRuleValue returns the value of the given root tag if it is a game rule, as described by GameRules.
+func gameRuleValue(t *nbt.NBTTag) (interface{}, bool) {
+	if t.Name == "" || t.Name != strings.ToLower(t.Name) {
+		return nil, false
+	}
+
+	switch v := t.Value.(type) {
+	case int8:
+		return v != 0, true
+	case int32:
+		return int(v), true
+	}
+
+	return nil, false
+}
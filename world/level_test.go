@@ -0,0 +1,161 @@
+package world
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/danhale-git/mine/nbt"
+)
+
+func testLevelData() *LevelData {
+	return &LevelData{StorageVersion: 9, Root: nbt.NBTTag{Type: nbt.TagCompound, Value: []nbt.NBTTag{
+		{Type: nbt.TagString, Name: "LevelName", Value: "Test"},
+		{Type: nbt.TagInt, Name: "SpawnX", Value: int32(1)},
+		{Type: nbt.TagInt, Name: "SpawnY", Value: int32(64)},
+		{Type: nbt.TagInt, Name: "SpawnZ", Value: int32(-3)},
+		{Type: nbt.TagLong, Name: "RandomSeed", Value: int64(-42)},
+		{Type: nbt.TagLong, Name: "Time", Value: int64(6000)},
+		{Type: nbt.TagInt, Name: "Difficulty", Value: int32(2)},
+		{Type: nbt.TagByte, Name: "keepinventory", Value: int8(0)},
+		{Type: nbt.TagInt, Name: "randomtickspeed", Value: int32(1)},
+		{Type: nbt.TagByte, Name: "futurerule", Value: int8(1)},
+		{Type: nbt.TagByte, Name: "spawnMobs", Value: int8(1)},
+		{Type: nbt.TagCompound, Name: "experiments", Value: []nbt.NBTTag{
+			{Type: nbt.TagByte, Name: "data_driven_items", Value: int8(1)},
+		}},
+		{Type: nbt.TagList, Name: "unknown", Value: nbt.List{Type: nbt.TagString, Values: []interface{}{"a", "b"}}},
+	}}}
+}
+
+func TestLevelDataRoundTrip(t *testing.T) {
+	l := testLevelData()
+
+	buf := bytes.Buffer{}
+	if err := l.Encode(&buf); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	got, err := DecodeLevelData(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !reflect.DeepEqual(l, got) {
+		t.Errorf("expected %+v: got %+v", l, got)
+	}
+}
+
+func TestLevelDataValues(t *testing.T) {
+	l := testLevelData()
+
+	if p := l.SpawnPoint(); p != (Position{1, 64, -3}) {
+		t.Errorf("unexpected spawn point %+v", p)
+	}
+
+	if l.Seed() != -42 || l.Time() != 6000 || l.Difficulty() != Normal || l.LevelName() != "Test" {
+		t.Errorf("unexpected seed %d, time %d, difficulty %s or name '%s'", l.Seed(), l.Time(), l.Difficulty(), l.LevelName())
+	}
+
+	// Rules which are not known are read, settings which are not rules are not
+	want := map[string]interface{}{"keepinventory": false, "randomtickspeed": 1, "futurerule": true}
+	if got := l.GameRules(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected game rules %+v: got %+v", want, got)
+	}
+
+	if err := l.SetGameRule("keepinventory", true); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if v, _ := l.GameRule("keepinventory"); v != true {
+		t.Errorf("expected keepinventory to be set: got %v", v)
+	}
+
+	if err := l.SetGameRule("FutureRule", false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if v, _ := l.GameRule("futurerule"); v != false {
+		t.Errorf("expected futurerule to be set: got %v", v)
+	}
+
+	if _, ok := l.GameRule("spawnMobs"); ok {
+		t.Errorf("expected spawnMobs not to be a game rule")
+	}
+
+	if !KnownGameRule("keepInventory") || KnownGameRule("futurerule") {
+		t.Errorf("unexpected known game rules")
+	}
+
+	if err := l.SetGameRule("randomtickspeed", true); err == nil {
+		t.Errorf("expected error setting int game rule to a bool")
+	}
+
+	if err := l.SetGameRule("notarule", 1); err == nil {
+		t.Errorf("expected error setting unknown game rule")
+	}
+
+	if err := l.SetExperiment("upcoming_creator_features", true); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	wantExperiments := map[string]bool{"data_driven_items": true, "upcoming_creator_features": true}
+	if got := l.Experiments(); !reflect.DeepEqual(got, wantExperiments) {
+		t.Errorf("expected experiments %+v: got %+v", wantExperiments, got)
+	}
+
+	if err := l.SetSpawnPoint(Position{5, 70, 5}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if p := l.SpawnPoint(); p != (Position{5, 70, 5}) {
+		t.Errorf("unexpected spawn point %+v", p)
+	}
+
+	if _, ok := l.Root.Child("unknown"); !ok {
+		t.Errorf("unknown tag was removed")
+	}
+}
+
+func TestLevelDataWrite(t *testing.T) {
+	dir := t.TempDir()
+
+	l := testLevelData()
+	if err := l.Write(dir); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := l.SetTime(0); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := l.Write(dir); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	got, err := ReadLevelData(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if got.Time() != 0 {
+		t.Errorf("expected time to be written: got %d", got.Time())
+	}
+
+	f, err := os.Open(filepath.Join(dir, levelDatBackupName))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer f.Close()
+
+	old, err := DecodeLevelData(f)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if old.Time() != 6000 {
+		t.Errorf("expected backup to hold the previous level data: got time %d", old.Time())
+	}
+}
@@ -0,0 +1,242 @@
+package world
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/danhale-git/mine/nbt"
+)
+
+// NotSaved is the Region index of blocks in sub chunks which are not stored in the world database.
+const NotSaved = -1
+
+// Position is a set of world block coordinates.
+type Position struct {
+	X, Y, Z int
+}
+
+// Box is a bounding box between two corners, inclusive.
+type Box struct {
+	Min, Max Position // Min has the lowest x, y and z
+}
+
+// NewBox returns the box between the given corners, which may be given in any order.
+func NewBox(a, b Position) Box {
+	return Box{
+		Min: Position{min(a.X, b.X), min(a.Y, b.Y), min(a.Z, b.Z)},
+		Max: Position{max(a.X, b.X), max(a.Y, b.Y), max(a.Z, b.Z)},
+	}
+}
+
+// Contains reports whether the given world coordinates are inside the box.
+func (b Box) Contains(x, y, z int) bool {
+	return x >= b.Min.X && y >= b.Min.Y && z >= b.Min.Z && x <= b.Max.X && y <= b.Max.Y && z <= b.Max.Z
+}
+
+// containsSubChunk reports whether any of the sub chunk with the given sub chunk coordinates is inside the box.
+func (b Box) containsSubChunk(x, y, z int) bool {
+	o := Position{x * chunkSize, y * chunkSize, z * chunkSize}
+	e := chunkSize - 1
+
+	return o.X+e >= b.Min.X && o.Y+e >= b.Min.Y && o.Z+e >= b.Min.Z && o.X <= b.Max.X && o.Y <= b.Max.Y && o.Z <= b.Max.Z
+}
+
+// containsChunk reports whether any of the chunk with the given chunk coordinates is inside the box, at any height.
+func (b Box) containsChunk(x, z int) bool {
+	e := chunkSize - 1
+	return x*chunkSize+e >= b.Min.X && z*chunkSize+e >= b.Min.Z && x*chunkSize <= b.Max.X && z*chunkSize <= b.Max.Z
+}
+
+// coversSubChunk reports whether all of the sub chunk with the given sub chunk coordinates is inside the box.
+func (b Box) coversSubChunk(x, y, z int) bool {
+	e := chunkSize - 1
+	return b.Contains(x*chunkSize, y*chunkSize, z*chunkSize) && b.Contains(x*chunkSize+e, y*chunkSize+e, z*chunkSize+e)
+}
+
+// Region is a dense volume of blocks between two corners. Each block is stored as an index into Palette, ordered by x
+// then z then y, matching sub chunk storage.
+type Region struct {
+	Min, Max  Position // Inclusive corners, Min having the lowest x, y and z
+	Dimension int
+
+	// Palette holds each distinct block in the region, including its Liquid. Palette block coordinates are zero.
+	Palette []Block
+
+	// Indices holds the Palette index of each block, or NotSaved.
+	Indices []int
+}
+
+// Size returns the number of blocks along each axis of the region.
+func (r *Region) Size() (x, y, z int) {
+	return r.Max.X - r.Min.X + 1, r.Max.Y - r.Min.Y + 1, r.Max.Z - r.Min.Z + 1
+}
+
+// Index returns the position in Indices of the given world coordinates. The boolean is false if the coordinates are
+// outside the region.
+func (r *Region) Index(x, y, z int) (int, bool) {
+	if !(Box{r.Min, r.Max}).Contains(x, y, z) {
+		return 0, false
+	}
+
+	_, sy, sz := r.Size()
+
+	return (y - r.Min.Y) + (z-r.Min.Z)*sy + (x-r.Min.X)*sy*sz, true
+}
+
+// Block returns the block at the given world coordinates. The boolean is false if the coordinates are outside the
+// region or in a sub chunk which is not saved.
+func (r *Region) Block(x, y, z int) (Block, bool) {
+	i, ok := r.Index(x, y, z)
+	if !ok || r.Indices[i] == NotSaved {
+		return Block{}, false
+	}
+
+	b := r.Palette[r.Indices[i]]
+	b.X, b.Y, b.Z = x, y, z
+
+	if b.Liquid != nil {
+		l := *b.Liquid
+		l.X, l.Y, l.Z = x, y, z
+		b.Liquid = &l
+	}
+
+	return b, true
+}
+
+// GetRegion returns the blocks in the box between the given corners, inclusive. The corners may be given in any order.
+// Sub chunks are read once each and walked in storage order. Blocks in sub chunks which are not saved have the index
+// NotSaved.
+func (w *World) GetRegion(a, b Position, dimension int) (*Region, error) {
+	box := NewBox(a, b)
+
+	r := &Region{
+		Min:       box.Min,
+		Max:       box.Max,
+		Dimension: dimension,
+	}
+
+	sx, sy, sz := r.Size()
+	r.Indices = make([]int, sx*sy*sz)
+
+	// Region palette indices keyed by the encoded palette entries of both layers
+	palette := make(map[string]int)
+
+	minSub := subChunkOrigin(r.Min.X, r.Min.Y, r.Min.Z, dimension)
+	maxSub := subChunkOrigin(r.Max.X, r.Max.Y, r.Max.Z, dimension)
+
+	for cx := minSub.x; cx <= maxSub.x; cx++ {
+		for cz := minSub.z; cz <= maxSub.z; cz++ {
+			for cy := minSub.y; cy <= maxSub.y; cy++ {
+				if err := w.readRegionSubChunk(r, palette, cx, cy, cz); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return r, nil
+}
+
+// readRegionSubChunk copies the blocks from the sub chunk with the given origin into the region.
+func (w *World) readRegionSubChunk(r *Region, palette map[string]int, cx, cy, cz int) error {
+	ox, oy, oz := cx*chunkSize, cy*chunkSize, cz*chunkSize
+
+	// The intersection of the sub chunk and the region in world coordinates
+	x0, x1 := max(ox, r.Min.X), min(ox+chunkSize-1, r.Max.X)
+	y0, y1 := max(oy, r.Min.Y), min(oy+chunkSize-1, r.Max.Y)
+	z0, z1 := max(oz, r.Min.Z), min(oz+chunkSize-1, r.Max.Z)
+
+	sc, release, err := w.subChunk(ox, oy, oz, r.Dimension)
+	if err != nil && !errors.Is(err, &SubChunkNotSavedError{}) {
+		return err
+	}
+
+	if sc != nil {
+		defer release()
+
+		sc.mu.RLock()
+		defer sc.mu.RUnlock()
+	}
+
+	// Region palette index for each combination of sub chunk palette indices, so each entry is only encoded once
+	local := make(map[[2]int]int)
+
+	for x := x0; x <= x1; x++ {
+		for z := z0; z <= z1; z++ {
+			for y := y0; y <= y1; y++ {
+				i, _ := r.Index(x, y, z)
+
+				if sc == nil {
+					r.Indices[i] = NotSaved
+					continue
+				}
+
+				voxelIndex, err := subChunkVoxelToIndex(x-ox, y-oy, z-oz)
+				if err != nil {
+					return err
+				}
+
+				l := [2]int{sc.Layers[0].Indices[voxelIndex], -1}
+				if len(sc.Layers) > 1 {
+					l[1] = sc.Layers[1].Indices[voxelIndex]
+				}
+
+				p, ok := local[l]
+				if !ok {
+					p, err = regionPaletteIndex(r, palette, sc, voxelIndex)
+					if err != nil {
+						return err
+					}
+					local[l] = p
+				}
+
+				r.Indices[i] = p
+			}
+		}
+	}
+
+	return nil
+}
+
+// regionPaletteIndex returns the region palette index of the block at the given storage index in the sub chunk, adding
+// it to the region palette if needed.
+func regionPaletteIndex(r *Region, palette map[string]int, sc *subChunkData, voxelIndex int) (int, error) {
+	buf := bytes.Buffer{}
+
+	state, _ := sc.blockState(0, voxelIndex)
+	if err := nbt.Write(&buf, *state); err != nil {
+		return 0, fmt.Errorf("encoding palette entry: %w", err)
+	}
+
+	if liquid, ok := sc.blockState(1, voxelIndex); ok && liquid.BlockID() != airID {
+		if err := nbt.Write(&buf, *liquid); err != nil {
+			return 0, fmt.Errorf("encoding palette entry: %w", err)
+		}
+	}
+
+	key := buf.String()
+
+	if p, ok := palette[key]; ok {
+		return p, nil
+	}
+
+	r.Palette = append(r.Palette, subChunkBlock(sc, voxelIndex, 0, 0, 0))
+	palette[key] = len(r.Palette) - 1
+
+	return len(r.Palette) - 1, nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,69 @@
+package world
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/danhale-git/mine/leveldb"
+	"github.com/danhale-git/mine/mock"
+)
+
+func TestGetRegion(t *testing.T) {
+	w := newWorld(mock.ValidLevelDB())
+
+	// Spans four sub chunks, given with corners out of order
+	r, err := w.GetRegion(Position{1, 3, -2}, Position{-2, 0, 1}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if r.Min != (Position{-2, 0, -2}) || r.Max != (Position{1, 3, 1}) {
+		t.Errorf("unexpected corners %+v %+v", r.Min, r.Max)
+	}
+
+	if len(r.Indices) != 4*4*4 {
+		t.Errorf("expected %d indices: got %d", 4*4*4, len(r.Indices))
+	}
+
+	for x := -2; x <= 1; x++ {
+		for y := 0; y <= 3; y++ {
+			for z := -2; z <= 1; z++ {
+				want, err := w.GetBlock(x, y, z, 0)
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+
+				got, ok := r.Block(x, y, z)
+				if !ok {
+					t.Fatalf("block %d %d %d not found in region", x, y, z)
+				}
+
+				if !reflect.DeepEqual(want, got) {
+					t.Errorf("block %d %d %d: expected %+v: got %+v", x, y, z, want, got)
+				}
+			}
+		}
+	}
+
+	if _, ok := r.Block(2, 0, 0); ok {
+		t.Errorf("expected block outside region not to be found")
+	}
+}
+
+func TestGetRegionNotSaved(t *testing.T) {
+	w := newWorld(mock.LevelDBWithKeys(leveldb.NewSubChunkKey(0, 0, 0, 0).Bytes()))
+
+	r, err := w.GetRegion(Position{0, 0, 0}, Position{0, 16, 0}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, ok := r.Block(0, 0, 0); !ok {
+		t.Errorf("expected block in saved sub chunk to be found")
+	}
+
+	i, _ := r.Index(0, 16, 0)
+	if r.Indices[i] != NotSaved {
+		t.Errorf("expected block in missing sub chunk to have index %d: got %d", NotSaved, r.Indices[i])
+	}
+}
@@ -0,0 +1,167 @@
+package world
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// ReplaceBlocks replaces every block in the given dimension for which match returns true with the given block, and
+// returns the number of blocks replaced. match is called with palette entries, which have zero coordinates and no
+// Liquid. Blocks which already have the state of the given block are not replaced or counted. Only the first storage
+// layer is changed.
+//
+// Sub chunks are scanned as described by Scan to find those with matching palette entries. Each of these is then
+// changed as SetBlock changes a sub chunk, so concurrent edits are not lost. If a sub chunk is entirely inside
+// c.Bounds, or c.Bounds is not set, matching palette entries are rewritten. Otherwise only the indices of blocks inside
+// the bounds are changed. Each modified sub chunk is written back to the database.
+func (w *World) ReplaceBlocks(ctx context.Context, dimension int, c ScanConfig, match func(Block) bool, to Block) (int, error) {
+	mu := sync.Mutex{}
+	replaced := 0
+
+	err := w.Scan(ctx, dimension, c, func(s *SubChunk) error {
+		var inside func(i int) bool
+
+		if c.Bounds != nil && !c.Bounds.containsSubChunk(s.X, s.Y, s.Z) {
+			return nil
+		}
+
+		found := false
+		for _, b := range s.Palette() {
+			if match(b) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil
+		}
+
+		o := s.Origin()
+
+		if c.Bounds != nil && !c.Bounds.coversSubChunk(s.X, s.Y, s.Z) {
+			inside = func(i int) bool {
+				x, y, z := subChunkIndexToVoxel(i)
+				return c.Bounds.Contains(o.X+x, o.Y+y, o.Z+z)
+			}
+		}
+
+		n, err := w.replaceSubChunk(o, dimension, match, to, inside)
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		replaced += n
+
+		return nil
+	})
+
+	return replaced, err
+}
+
+// replaceSubChunk replaces matching blocks in the sub chunk with the given origin, holding its write lock, and writes it
+// back to the database if any were replaced.
+func (w *World) replaceSubChunk(o Position, dimension int, match func(Block) bool, to Block, inside func(i int) bool) (int, error) {
+	sc, release, err := w.subChunk(o.X, o.Y, o.Z, dimension)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	n, err := sc.Layers[0].replace(match, to, inside)
+	if err != nil {
+		// The cached sub chunk may be partly changed, so read it again when next used
+		w.Invalidate(o.X, o.Y, o.Z, dimension)
+		return 0, err
+	}
+
+	if n == 0 {
+		return 0, nil
+	}
+
+	if err := w.putSubChunk(sc, o.X, o.Y, o.Z, dimension); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// replace replaces blocks whose palette entries match with the given block, and returns the number of blocks replaced.
+// If inside is nil every matching block is replaced by rewriting its palette entry, otherwise only blocks at storage
+// indices for which inside returns true are replaced.
+func (b *blockStorage) replace(match func(Block) bool, to Block, inside func(i int) bool) (int, error) {
+	want, err := b.paletteEntry(to.ID, to.States, to.Version)
+	if err != nil {
+		return 0, fmt.Errorf("encoding block state: %w", err)
+	}
+
+	matched := make(map[int]bool)
+
+	for p := range b.Palette {
+		// Blocks which already have the target state are not replaced
+		if sameBlockState(&b.Palette[p], &want) {
+			continue
+		}
+
+		if match(newBlock(&b.Palette[p], 0, 0, 0)) {
+			matched[p] = true
+		}
+	}
+
+	if len(matched) == 0 {
+		return 0, nil
+	}
+
+	counts := make(map[int]int)
+	for i, p := range b.Indices {
+		if matched[p] && (inside == nil || inside(i)) {
+			counts[p]++
+		}
+	}
+
+	replaced := 0
+	for _, n := range counts {
+		replaced += n
+	}
+
+	if replaced == 0 {
+		return 0, nil
+	}
+
+	existing := len(b.Palette)
+
+	target, err := b.paletteIndex(to.ID, to.States, to.Version)
+	if err != nil {
+		return 0, fmt.Errorf("adding block to palette: %w", err)
+	}
+
+	// If the target state is new, rewrite the first matched palette entry in place rather than adding it. Only the
+	// indices of any other matched entries then need changing.
+	if inside == nil && target == existing {
+		first := existing
+		for p := range matched {
+			if p < first {
+				first = p
+			}
+		}
+
+		b.Palette[first] = b.Palette[target]
+		b.Palette = b.Palette[:existing]
+		target = first
+	}
+
+	for i, p := range b.Indices {
+		if p != target && matched[p] && (inside == nil || inside(i)) {
+			b.Indices[i] = target
+		}
+	}
+
+	return replaced, nil
+}
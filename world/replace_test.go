@@ -0,0 +1,96 @@
+package world
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReplaceBlocks(t *testing.T) {
+	w := scanTestWorld()
+
+	isFence := func(b Block) bool { return b.ID == "minecraft:fence" }
+	fence, err := w.GetBlock(0, 1, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !isFence(fence) {
+		t.Fatalf("expected test block to be a fence: got %+v", fence)
+	}
+
+	crimson := Block{ID: "minecraft:crimson_fence"}
+
+	// Only the first sub chunk is fully covered, the second is partly covered
+	box := NewBox(Position{0, 0, 0}, Position{15, 17, 15})
+
+	n, err := w.ReplaceBlocks(context.Background(), 0, ScanConfig{Bounds: &box}, isFence, crimson)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if n == 0 {
+		t.Fatalf("no blocks were replaced")
+	}
+
+	count := 0
+
+	err = w.ScanBlocks(context.Background(), 0, ScanConfig{Workers: 1}, func(b Block) error {
+		switch {
+		case box.Contains(b.X, b.Y, b.Z) && isFence(b):
+			t.Errorf("block %d %d %d was not replaced", b.X, b.Y, b.Z)
+		case !box.Contains(b.X, b.Y, b.Z) && b.ID == crimson.ID:
+			t.Errorf("block %d %d %d outside of bounds was replaced", b.X, b.Y, b.Z)
+		case b.ID == crimson.ID:
+			count++
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if count != n {
+		t.Errorf("expected %d replaced blocks: found %d", n, count)
+	}
+
+	// Blocks which already have the target state are not counted
+	if n, err := w.ReplaceBlocks(context.Background(), 0, ScanConfig{}, func(b Block) bool { return b.ID == crimson.ID }, crimson); err != nil || n != 0 {
+		t.Errorf("expected no blocks to be replaced: got %d, %v", n, err)
+	}
+
+	// Water logging is kept and the cache is not stale
+	b, err := w.GetBlock(0, 1, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if b.ID != crimson.ID || !b.WaterLogged {
+		t.Errorf("expected water logged crimson fence: got %+v", b)
+	}
+}
+
+func TestBlockStorageReplacePalette(t *testing.T) {
+	w := scanTestWorld()
+
+	sc, err := w.readSubChunk(0, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	l := &sc.Layers[0]
+	size := len(l.Palette)
+
+	n, err := l.replace(func(b Block) bool { return b.ID == "minecraft:fence" }, Block{ID: "minecraft:crimson_fence"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if n == 0 {
+		t.Fatalf("no blocks were replaced")
+	}
+
+	if len(l.Palette) != size {
+		t.Errorf("expected palette entry to be rewritten in place: palette size changed from %d to %d", size, len(l.Palette))
+	}
+}
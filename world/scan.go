@@ -0,0 +1,248 @@
+package world
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// ScanConfig controls a world scan.
+type ScanConfig struct {
+	// Workers is the number of sub chunks decoded in parallel. It defaults to the number of CPUs.
+	Workers int
+
+	// Progress, if set, is called after each sub chunk is processed with the number processed so far and the total.
+	// Calls are serialised.
+	Progress func(done, total int)
+
+	// OnError, if set, is called with any error reading or decoding a sub chunk, such as a *CorruptStorageError. If
+	// it returns nil the sub chunk is skipped and the scan continues, otherwise the scan stops and returns the error.
+	// If OnError is not set, the first such error stops the scan. Calls are serialised.
+	OnError func(err error) error
+
+	// Bounds, if set, limits the scan to sub chunks which are at least partly inside the box. ScanBlocks and
+	// FindBlocks only report blocks inside the box.
+	Bounds *Box
+}
+
+// SubChunk is a 16x16x16 block section of a chunk, passed to Scan callbacks.
+type SubChunk struct {
+	X, Y, Z   int // Sub chunk coordinates, which are world coordinates divided by 16
+	Dimension int
+
+	sc *subChunkData
+}
+
+// Origin returns the world coordinates of the block with the lowest x, y and z values.
+func (s *SubChunk) Origin() Position {
+	return Position{s.X * chunkSize, s.Y * chunkSize, s.Z * chunkSize}
+}
+
+// Palette returns each distinct block state in the sub chunk's first storage layer. Block coordinates are zero. A
+// block in the palette is not necessarily present in the sub chunk.
+func (s *SubChunk) Palette() []Block {
+	p := s.sc.Layers[0].Palette
+	blocks := make([]Block, len(p))
+
+	for i := range p {
+		blocks[i] = newBlock(&p[i], 0, 0, 0)
+	}
+
+	return blocks
+}
+
+// Blocks calls fn for each block in the sub chunk in storage order, stopping if fn returns an error. Blocks with the
+// same state share the same States map, which must not be modified.
+func (s *SubChunk) Blocks(fn func(Block) error) error {
+	o := s.Origin()
+
+	// Blocks for each combination of layer palette indices, so each palette entry is only converted once
+	blocks := make(map[[2]int]Block)
+
+	for i := 0; i < subChunkBlockCount; i++ {
+		l := [2]int{s.sc.Layers[0].Indices[i], -1}
+		if len(s.sc.Layers) > 1 {
+			l[1] = s.sc.Layers[1].Indices[i]
+		}
+
+		b, ok := blocks[l]
+		if !ok {
+			b = subChunkBlock(s.sc, i, 0, 0, 0)
+			blocks[l] = b
+		}
+
+		x, y, z := subChunkIndexToVoxel(i)
+		b.X, b.Y, b.Z = o.X+x, o.Y+y, o.Z+z
+
+		if b.Liquid != nil {
+			liquid := *b.Liquid
+			liquid.X, liquid.Y, liquid.Z = b.X, b.Y, b.Z
+			b.Liquid = &liquid
+		}
+
+		if err := fn(b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Scan calls fn for every sub chunk stored in the given dimension. Sub chunks are read and decoded by a pool of
+// workers and fn is called concurrently from those workers, so it must be safe for concurrent use. Sub chunks read by
+// a scan are not cached.
+//
+// The scan stops when ctx is cancelled, returning the context's error, or when fn returns an error, returning that
+// error.
+func (w *World) Scan(ctx context.Context, dimension int, c ScanConfig, fn func(*SubChunk) error) error {
+	chunks, err := w.Chunks(dimension)
+	if err != nil {
+		return fmt.Errorf("listing chunks: %w", err)
+	}
+
+	jobs := make([]SubChunk, 0)
+	for _, ch := range chunks {
+		for _, y := range ch.SubChunks {
+			if c.Bounds != nil && !c.Bounds.containsSubChunk(ch.X, y, ch.Z) {
+				continue
+			}
+			jobs = append(jobs, SubChunk{X: ch.X, Y: y, Z: ch.Z, Dimension: dimension})
+		}
+	}
+
+	workers := c.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	scanCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex // Guards done, scanErr and calls to c.Progress and c.OnError
+	var done int
+	var scanErr error
+
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if scanErr == nil {
+			scanErr = err
+			cancel()
+		}
+	}
+
+	progress := func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		done++
+		if c.Progress != nil {
+			c.Progress(done, len(jobs))
+		}
+	}
+
+	handleReadError := func(err error) error {
+		if c.OnError == nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		return c.OnError(err)
+	}
+
+	jobChan := make(chan SubChunk)
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for s := range jobChan {
+				if scanCtx.Err() != nil {
+					continue
+				}
+
+				o := s.Origin()
+
+				sc, err := w.readSubChunk(o.X, o.Y, o.Z, dimension)
+				if err != nil {
+					if err = handleReadError(err); err != nil {
+						fail(err)
+						continue
+					}
+				} else {
+					s.sc = sc
+					if err := fn(&s); err != nil {
+						fail(err)
+						continue
+					}
+				}
+
+				progress()
+			}
+		}()
+	}
+
+feed:
+	for _, j := range jobs {
+		select {
+		case jobChan <- j:
+		case <-scanCtx.Done():
+			break feed
+		}
+	}
+
+	close(jobChan)
+	wg.Wait()
+
+	if scanErr != nil {
+		return scanErr
+	}
+
+	return ctx.Err()
+}
+
+// ScanBlocks calls fn for every block in every sub chunk stored in the given dimension. It is called concurrently as
+// described by Scan.
+func (w *World) ScanBlocks(ctx context.Context, dimension int, c ScanConfig, fn func(Block) error) error {
+	return w.FindBlocks(ctx, dimension, c, nil, fn)
+}
+
+// FindBlocks calls fn for every block in the given dimension for which match returns true. match is first called with
+// each entry in a sub chunk's palette, which have zero coordinates and no Liquid, and sub chunks with no matching
+// entries are skipped. A nil match matches every block. fn is called concurrently as described by Scan.
+func (w *World) FindBlocks(ctx context.Context, dimension int, c ScanConfig, match func(Block) bool, fn func(Block) error) error {
+	return w.Scan(ctx, dimension, c, func(s *SubChunk) error {
+		if match != nil {
+			found := false
+			for _, b := range s.Palette() {
+				if match(b) {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				return nil
+			}
+		}
+
+		return s.Blocks(func(b Block) error {
+			if c.Bounds != nil && !c.Bounds.Contains(b.X, b.Y, b.Z) {
+				return nil
+			}
+
+			if match != nil && !match(b) {
+				return nil
+			}
+
+			return fn(b)
+		})
+	})
+}
@@ -0,0 +1,195 @@
+package world
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/danhale-git/mine/leveldb"
+	"github.com/danhale-git/mine/mock"
+)
+
+func scanTestWorld() *World {
+	return newWorld(mock.LevelDBWithKeys(
+		leveldb.NewSubChunkKey(0, 0, 0, 0).Bytes(),
+		leveldb.NewSubChunkKey(0, 0, 0, 1).Bytes(),
+		leveldb.NewSubChunkKey(-1, 2, 0, 0).Bytes(),
+		leveldb.NewSubChunkKey(5, 5, 1, 0).Bytes(),
+	))
+}
+
+func TestScan(t *testing.T) {
+	w := scanTestWorld()
+
+	mu := sync.Mutex{}
+	origins := make(map[Position]bool)
+	var progress []int
+
+	c := ScanConfig{
+		Workers:  2,
+		Progress: func(done, total int) { progress = append(progress, done, total) },
+	}
+
+	err := w.Scan(context.Background(), 0, c, func(s *SubChunk) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		origins[s.Origin()] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, o := range []Position{{0, 0, 0}, {0, 16, 0}, {-16, 0, 32}} {
+		if !origins[o] {
+			t.Errorf("sub chunk with origin %+v was not scanned", o)
+		}
+	}
+
+	if len(origins) != 3 {
+		t.Errorf("expected 3 sub chunks: got %d", len(origins))
+	}
+
+	if len(progress) != 6 || progress[4] != 3 || progress[5] != 3 {
+		t.Errorf("unexpected progress calls: %v", progress)
+	}
+}
+
+func TestScanBlocks(t *testing.T) {
+	w := scanTestWorld()
+
+	mu := sync.Mutex{}
+	count := 0
+
+	err := w.ScanBlocks(context.Background(), 0, ScanConfig{}, func(b Block) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		count++
+
+		if b.X == 0 && b.Y == 1 && b.Z == 0 && !b.WaterLogged {
+			t.Errorf("expected block 0 1 0 to be water logged")
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if count != 3*subChunkBlockCount {
+		t.Errorf("expected %d blocks: got %d", 3*subChunkBlockCount, count)
+	}
+
+	// Scanned blocks should match those returned by GetBlock
+	s := SubChunk{X: -1, Y: 0, Z: 2}
+	s.sc, _ = w.readSubChunk(-16, 0, 32, 0)
+
+	_ = s.Blocks(func(b Block) error {
+		want, err := w.GetBlock(b.X, b.Y, b.Z, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if want.ID != b.ID || want.WaterLogged != b.WaterLogged {
+			t.Fatalf("block %d %d %d: expected %+v: got %+v", b.X, b.Y, b.Z, want, b)
+		}
+
+		return nil
+	})
+}
+
+func TestScanErrors(t *testing.T) {
+	w := scanTestWorld()
+	stop := errors.New("stop")
+
+	err := w.Scan(context.Background(), 0, ScanConfig{Workers: 1}, func(s *SubChunk) error {
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("expected callback error to be returned: got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = w.Scan(ctx, 0, ScanConfig{}, func(s *SubChunk) error { return nil })
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled: got %v", err)
+	}
+
+	key := leveldb.NewSubChunkKey(0, 0, 0, 0).Bytes()
+	_ = w.db.Put(key, []byte{8, 1, 8})
+
+	var corrupt *CorruptStorageError
+
+	err = w.Scan(context.Background(), 0, ScanConfig{}, func(s *SubChunk) error { return nil })
+	if !errors.As(err, &corrupt) {
+		t.Errorf("expected *CorruptStorageError: got %v", err)
+	}
+
+	skipped := 0
+	c := ScanConfig{OnError: func(err error) error {
+		skipped++
+		return nil
+	}}
+
+	if err = w.Scan(context.Background(), 0, c, func(s *SubChunk) error { return nil }); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	if skipped != 1 {
+		t.Errorf("expected 1 skipped sub chunk: got %d", skipped)
+	}
+}
+
+func TestFindBlocks(t *testing.T) {
+	w := scanTestWorld()
+
+	mu := sync.Mutex{}
+	found := make([]Block, 0)
+
+	box := NewBox(Position{0, 0, 0}, Position{15, 31, 15})
+	c := ScanConfig{Bounds: &box}
+
+	err := w.FindBlocks(context.Background(), 0, c, func(b Block) bool {
+		return b.ID == "minecraft:fence"
+	}, func(b Block) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		found = append(found, b)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(found) == 0 {
+		t.Fatalf("no blocks found")
+	}
+
+	for _, b := range found {
+		if b.ID != "minecraft:fence" || !box.Contains(b.X, b.Y, b.Z) {
+			t.Errorf("unexpected block found: %+v", b)
+		}
+	}
+
+	searched := 0
+
+	err = w.FindBlocks(context.Background(), 0, ScanConfig{}, func(b Block) bool {
+		return b.ID == "minecraft:spawner"
+	}, func(b Block) error {
+		searched++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if searched != 0 {
+		t.Errorf("expected no blocks to be found: found %d", searched)
+	}
+}
@@ -3,24 +3,42 @@ package world
 import (
 	"bytes"
 	"encoding/binary"
-	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"math"
+	"reflect"
+	"sync"
 
 	"github.com/danhale-git/mine/nbt"
-	"github.com/danhale-git/nbt2json"
 )
 
 const subChunkBlockCount = 4096
 const chunkSize = 16
 
+// Sub chunk format versions. Version 9 adds the sub chunk Y index after the storage count.
+const (
+	subChunkVersionLegacy   = 1
+	subChunkVersionStorages = 8
+	subChunkVersionYIndex   = 9
+)
+
+// defaultBlockVersion is used for new palette entries when the palette has no existing entry to copy a version from.
+const defaultBlockVersion = 17879555
+
+// validBitsPerBlock are the index sizes supported by the Bedrock block storage format, smallest first.
+var validBitsPerBlock = []int{1, 2, 3, 4, 5, 6, 8, 16}
+
 // subChunkData is the parsed data for one 16x16 subchunk. A palette including all block states in the subchunk is indexed
 // by a slice of integers (one for each block) to determine the state and block id for each block in the palette.
 type subChunkData struct {
-	Blocks      blockStorage
-	WaterLogged blockStorage
+	mu sync.RWMutex // Guards all fields once the sub chunk is cached
+
+	Version int8
+	Y       int8 // The sub chunk Y index, which may be negative
+
+	// Layers are the block storage layers. Layer 0 holds the blocks and any further layers hold blocks which share
+	// the same space, usually water for water logged blocks but also other liquids or snow.
+	Layers []blockStorage
 }
 
 type blockStorage struct {
@@ -41,15 +59,20 @@ func subChunkOrigin(x, y, z, d int) struct{ x, y, z, d int } {
 
 // worldVoxelToSubChunk returns the coordinates relative to sub chunk origin, from the given world coordinates.
 func worldVoxelToSubChunk(x, y, z int) (sx, sy, sz int) {
-	return x % chunkSize, y % chunkSize, z % chunkSize
+	return mod(x, chunkSize), mod(y, chunkSize), mod(z, chunkSize)
+}
+
+// mod returns the non-negative remainder of a / b, so that negative coordinates map to the correct sub chunk offset.
+func mod(a, b int) int {
+	return ((a % b) + b) % b
 }
 
 // voxelToIndex returns the block storage index from the given sub chunk x y and z coordinates.
-func subChunkVoxelToIndex(x, y, z int) int {
-	if x > 15 || y > 15 || z > 15 {
-		log.Panicf("coordinates %d %d %d are invalid: sub chunk cooridnates may not exceed 0-15", x, y, z)
+func subChunkVoxelToIndex(x, y, z int) (int, error) {
+	if x < 0 || y < 0 || z < 0 || x > 15 || y > 15 || z > 15 {
+		return 0, fmt.Errorf("coordinates %d %d %d are invalid: sub chunk coordinates must be within 0-15", x, y, z)
 	}
-	return y + z*16 + x*16*16
+	return y + z*16 + x*16*16, nil
 }
 
 // indexToVoxel returns the world x y z offset from the sub chunk root for the given block storage index.
@@ -61,67 +84,156 @@ func subChunkIndexToVoxel(i int) (x, y, z int) {
 	return
 }
 
-func parseSubChunk(data []byte) (*subChunkData, error) {
+// parseSubChunk parses a sub chunk value. y is the sub chunk Y index from the key, which is validated against the
+// index stored in version 9 sub chunks. An *UnsupportedVersionError or *CorruptStorageError is returned if the value
+// can't be parsed.
+func parseSubChunk(data []byte, y int) (*subChunkData, error) {
 	r := bytes.NewReader(data)
-	s := subChunkData{}
+	s := subChunkData{Y: int8(y)}
 
-	var version int8
-	if err := readLittleEndian(r, &version); err != nil {
-		return nil, fmt.Errorf("reading version byte: %w", err)
+	if err := readLittleEndian(r, &s.Version); err != nil {
+		return nil, corruptStorage(r, fmt.Errorf("reading version byte: %w", err))
 	}
 
 	var storageCount int8
 
-	switch version {
-	case 1:
+	switch s.Version {
+	case subChunkVersionLegacy:
 		storageCount = 1
-	case 8:
+	case subChunkVersionStorages, subChunkVersionYIndex:
 		if err := readLittleEndian(r, &storageCount); err != nil {
-			return nil, fmt.Errorf("reading storage count: %w", err)
+			return nil, corruptStorage(r, fmt.Errorf("reading storage count: %w", err))
 		}
 	default:
-		return nil, fmt.Errorf("unhandled subchunk block storage version: '%d'", version)
+		return nil, &UnsupportedVersionError{Version: s.Version}
 	}
 
-	var err error
+	if s.Version == subChunkVersionYIndex {
+		var yIndex int8
+		if err := readLittleEndian(r, &yIndex); err != nil {
+			return nil, corruptStorage(r, fmt.Errorf("reading y index: %w", err))
+		}
 
-	s.Blocks.Indices, s.Blocks.Palette, err = parseBlockStorage(r)
-	if err != nil {
-		return nil, fmt.Errorf("parsing water logged: %s", err)
+		if int(yIndex) != y {
+			return nil, corruptStorage(r, fmt.Errorf("sub chunk y index %d does not match key y index %d", yIndex, y))
+		}
 	}
 
 	// https://minecraft.fandom.com/wiki/Bedrock_Edition_level_format
 	// In the majority of cases, there is only one storage record.
-	// A second record may be present to indicate block water-logging.
-	switch storageCount {
-	case 0:
-		panic("block storage count is 0")
-	case 1:
-		// Block storage has already been parsed above
-	case 2:
-		// Parse second block storage as water logged if it exists
-		s.WaterLogged.Indices, s.WaterLogged.Palette, err = parseBlockStorage(r)
+	// A second record may be present to indicate block water-logging, or hold other blocks in the same space.
+	if storageCount < 1 {
+		return nil, corruptStorage(r, fmt.Errorf("unhandled storage count: %d", storageCount))
+	}
+
+	s.Layers = make([]blockStorage, storageCount)
+
+	for i := range s.Layers {
+		var err error
+
+		s.Layers[i].Indices, s.Layers[i].Palette, err = parseBlockStorage(r)
 		if err != nil {
-			return nil, fmt.Errorf("parsing water logged: %s", err)
+			return nil, corruptStorage(r, fmt.Errorf("parsing storage layer %d: %w", i, err))
 		}
-		// Added some panicking here as the Minecraft level format seems changeable.
+	}
 
-		if len(s.WaterLogged.Palette) > 2 {
-			log.Panicf(`
-second block storage palette exceeded known max length of 2
-found these states - %+v`, s.WaterLogged.Palette)
-		}
-		if len(s.WaterLogged.Palette) > 1 && s.WaterLogged.Palette[1].BlockID() != waterID {
-			log.Panicf(`
-second block storage palette did not have '%s' at index 1 to indicate water logged blocks
-found id '%s' unexpectedly`, waterID, s.WaterLogged.Palette[1].BlockID())
+	return &s, nil
+}
+
+// blockState returns the palette entry for the block at the given storage index in the given layer. The boolean is
+// false if the sub chunk does not have that layer.
+func (s *subChunkData) blockState(layer, voxelIndex int) (*nbt.NBTTag, bool) {
+	if layer >= len(s.Layers) {
+		return nil, false
+	}
+
+	l := &s.Layers[layer]
+
+	return &l.Palette[l.Indices[voxelIndex]], true
+}
+
+// setBlockState sets the block at the given storage index in the given layer, adding the block to the palette if
+// needed. Missing layers up to the given layer are added and filled with air.
+func (s *subChunkData) setBlockState(layer, voxelIndex int, id string, states map[string]interface{}, version int32) error {
+	i, err := s.statePaletteIndex(layer, id, states, version)
+	if err != nil {
+		return err
+	}
+
+	s.Layers[layer].Indices[voxelIndex] = i
+
+	return nil
+}
+
+// statePaletteIndex returns the index of the given block state in the palette of the given layer, adding it if needed.
+// Missing layers up to the given layer are added and filled with air.
+func (s *subChunkData) statePaletteIndex(layer int, id string, states map[string]interface{}, version int32) (int, error) {
+	for len(s.Layers) <= layer {
+		l := blockStorage{Indices: make([]int, subChunkBlockCount)}
+
+		if _, err := l.paletteIndex(airID, nil, s.Layers[0].version()); err != nil {
+			return 0, fmt.Errorf("adding air to new layer: %w", err)
 		}
 
+		s.Layers = append(s.Layers, l)
+	}
+
+	i, err := s.Layers[layer].paletteIndex(id, states, version)
+	if err != nil {
+		return 0, fmt.Errorf("adding block to layer %d palette: %w", layer, err)
+	}
+
+	return i, nil
+}
+
+// blockPaletteIndices returns the palette indices of the given block in the first two layers, adding it to the palettes
+// if needed. The second layer holds the block's Liquid, water if it is WaterLogged, or otherwise air. The second index
+// is -1 if the block needs no second layer and the sub chunk has none.
+func (s *subChunkData) blockPaletteIndices(b Block) ([2]int, error) {
+	var p [2]int
+	var err error
+
+	if p[0], err = s.statePaletteIndex(0, b.ID, b.States, b.Version); err != nil {
+		return p, err
+	}
+
+	switch {
+	case b.Liquid != nil:
+		p[1], err = s.statePaletteIndex(1, b.Liquid.ID, b.Liquid.States, b.Liquid.Version)
+	case b.WaterLogged:
+		p[1], err = s.statePaletteIndex(1, waterID, map[string]interface{}{"liquid_depth": int32(0)}, 0)
+	case len(s.Layers) > 1:
+		p[1], err = s.statePaletteIndex(1, airID, nil, 0)
 	default:
-		log.Panicf("unhandled storage count: %d", storageCount)
+		p[1] = -1
 	}
 
-	return &s, nil
+	return p, err
+}
+
+// setBlockIndices sets the palette indices of the block at the given storage index in the first two layers, as
+// returned by blockPaletteIndices.
+func (s *subChunkData) setBlockIndices(voxelIndex int, p [2]int) {
+	s.Layers[0].Indices[voxelIndex] = p[0]
+
+	if p[1] >= 0 {
+		s.Layers[1].Indices[voxelIndex] = p[1]
+	}
+}
+
+// newSubChunkData returns an empty sub chunk with the given Y index, holding a single layer of air.
+func newSubChunkData(y int) (*subChunkData, error) {
+	s := &subChunkData{
+		Version: subChunkVersionStorages,
+		Y:       int8(y),
+		Layers:  []blockStorage{{Indices: make([]int, subChunkBlockCount)}},
+	}
+
+	if _, err := s.Layers[0].paletteIndex(airID, nil, defaultBlockVersion); err != nil {
+		return nil, fmt.Errorf("adding air to palette: %w", err)
+	}
+
+	return s, nil
 }
 
 func parseBlockStorage(r *bytes.Reader) ([]int, []nbt.NBTTag, error) {
@@ -130,12 +242,18 @@ func parseBlockStorage(r *bytes.Reader) ([]int, []nbt.NBTTag, error) {
 
 	indices, err := stateIndices(r)
 	if err != nil {
-		return nil, nil, fmt.Errorf("parsing water logged indices: %s", err)
+		return nil, nil, fmt.Errorf("parsing indices: %w", err)
 	}
 
 	palette, err = statePalette(r)
 	if err != nil {
-		return nil, nil, fmt.Errorf("parsing nbt data: %s", err)
+		return nil, nil, fmt.Errorf("parsing nbt data: %w", err)
+	}
+
+	for i, p := range indices {
+		if p >= len(palette) {
+			return nil, nil, fmt.Errorf("index %d of block %d is out of range for palette of length %d", p, i, len(palette))
+		}
 	}
 
 	return indices, palette, nil
@@ -146,7 +264,7 @@ func parseBlockStorage(r *bytes.Reader) ([]int, []nbt.NBTTag, error) {
 func stateIndices(r *bytes.Reader) ([]int, error) {
 	var bitsPerBlockAndVersion byte
 	if err := readLittleEndian(r, &bitsPerBlockAndVersion); err != nil {
-		log.Fatalf("reading version byte: %s", err)
+		return nil, fmt.Errorf("reading version byte: %w", err)
 	}
 
 	bitsPerBlock := int(bitsPerBlockAndVersion >> 1)
@@ -156,6 +274,16 @@ func stateIndices(r *bytes.Reader) ([]int, error) {
 		return nil, fmt.Errorf("invalid block storage version %d: 0 is expected for save files", storageVersion)
 	}
 
+	return unpackIndices(r, bitsPerBlock)
+}
+
+// unpackIndices reads the 32 bit words of a block storage record following the bits per block and version byte, and
+// returns the packed indices.
+func unpackIndices(r *bytes.Reader, bitsPerBlock int) ([]int, error) {
+	if bitsPerBlock < 1 || bitsPerBlock > validBitsPerBlock[len(validBitsPerBlock)-1] {
+		return nil, fmt.Errorf("invalid bits per block %d", bitsPerBlock)
+	}
+
 	blocksPerWord := int(math.Floor(32.0 / float64(bitsPerBlock)))
 	wordCount := int(math.Ceil(subChunkBlockCount / float64(blocksPerWord)))
 
@@ -166,7 +294,7 @@ func stateIndices(r *bytes.Reader) ([]int, error) {
 	for w := 0; w < wordCount; w++ {
 		var word int32
 		if err := readLittleEndian(r, &word); err != nil {
-			return nil, fmt.Errorf("reading word %d from raw data: %s", w, err)
+			return nil, fmt.Errorf("reading word %d from raw data: %w", w, err)
 		}
 
 		for b := 0; b < blocksPerWord && i < subChunkBlockCount; b++ {
@@ -186,25 +314,209 @@ func statePalette(r *bytes.Reader) ([]nbt.NBTTag, error) {
 		return nil, fmt.Errorf("reading palette size bytes: %w", err)
 	}
 
-	j, err := nbt2json.ReadNbt2Json(r, "", int(paletteSize))
+	if paletteSize < 0 || int(paletteSize) > subChunkBlockCount {
+		return nil, fmt.Errorf("invalid palette size %d", paletteSize)
+	}
+
+	palette := make([]nbt.NBTTag, paletteSize)
+
+	for i := range palette {
+		t, err := nbt.Read(r)
+		if err != nil {
+			return nil, fmt.Errorf("reading palette entry %d: %w", i, err)
+		}
+
+		palette[i] = t
+	}
+
+	return palette, nil
+}
+
+// version returns the block version of the first palette entry, or defaultBlockVersion if it has none.
+func (b *blockStorage) version() int32 {
+	if len(b.Palette) > 0 {
+		if v, ok := b.Palette[0].BlockVersion(); ok {
+			return v
+		}
+	}
+
+	return defaultBlockVersion
+}
+
+// paletteIndex returns the index of the first palette entry with the given block id and states, adding a new entry to
+// the end of the palette if none exists. Block versions are not compared. If version is zero, new entries copy the
+// version of the first palette entry.
+func (b *blockStorage) paletteIndex(id string, states map[string]interface{}, version int32) (int, error) {
+	t, err := b.paletteEntry(id, states, version)
 	if err != nil {
-		return nil, fmt.Errorf("calling nbt2json, %w", err)
+		return 0, err
+	}
+
+	for i := range b.Palette {
+		if sameBlockState(&b.Palette[i], &t) {
+			return i, nil
+		}
+	}
+
+	b.Palette = append(b.Palette, t)
+
+	return len(b.Palette) - 1, nil
+}
+
+// paletteEntry returns a palette entry with the given block id and states. If version is zero, it has the version of
+// the first palette entry.
+func (b *blockStorage) paletteEntry(id string, states map[string]interface{}, version int32) (nbt.NBTTag, error) {
+	if version == 0 {
+		version = b.version()
+	}
+
+	// Build the entry first so that state values are converted to their stored types before comparing
+	return nbt.NewBlockState(id, states, version)
+}
+
+// sameBlockState reports whether two palette entries have the same block id and states. Block versions are not
+// compared.
+func sameBlockState(a, b *nbt.NBTTag) bool {
+	return a.BlockID() == b.BlockID() && reflect.DeepEqual(a.BlockStates(), b.BlockStates())
+}
+
+// compact removes palette entries which are not referenced by any index and updates the indices to match.
+func (b *blockStorage) compact() {
+	used := make([]bool, len(b.Palette))
+	for _, i := range b.Indices {
+		used[i] = true
+	}
+
+	remap := make([]int, len(b.Palette))
+	palette := make([]nbt.NBTTag, 0, len(b.Palette))
+
+	for i, t := range b.Palette {
+		if used[i] {
+			remap[i] = len(palette)
+			palette = append(palette, t)
+		}
+	}
+
+	for i, p := range b.Indices {
+		b.Indices[i] = remap[p]
+	}
+
+	b.Palette = palette
+}
+
+// encodeSubChunk returns the sub chunk data in the storage format it was read with, suitable for writing back to the
+// database. Legacy version 1 sub chunks are written as version 8.
+func encodeSubChunk(s *subChunkData) ([]byte, error) {
+	buf := bytes.Buffer{}
+
+	version := s.Version
+	if version != subChunkVersionYIndex {
+		version = subChunkVersionStorages
 	}
 
-	nbtData := struct {
-		NBT []nbt.NBTTag
-	}{}
-	if err := json.Unmarshal(j, &nbtData); err != nil {
-		return nil, fmt.Errorf("unmarshaling json, %w", err)
+	if err := writeLittleEndian(&buf, version); err != nil {
+		return nil, fmt.Errorf("writing version byte: %w", err)
 	}
 
-	if len(nbtData.NBT) != int(paletteSize) {
-		return nil, fmt.Errorf("%d nbt records returned for palette size of %d", len(nbtData.NBT), paletteSize)
+	if err := writeLittleEndian(&buf, int8(len(s.Layers))); err != nil {
+		return nil, fmt.Errorf("writing storage count: %w", err)
 	}
 
-	return nbtData.NBT, nil
+	if version == subChunkVersionYIndex {
+		if err := writeLittleEndian(&buf, s.Y); err != nil {
+			return nil, fmt.Errorf("writing y index: %w", err)
+		}
+	}
+
+	for i := range s.Layers {
+		b := &s.Layers[i]
+		b.compact()
+
+		if err := encodeBlockStorage(&buf, b); err != nil {
+			return nil, fmt.Errorf("encoding block storage %d: %w", i, err)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func encodeBlockStorage(w *bytes.Buffer, b *blockStorage) error {
+	if err := writeStateIndices(w, b.Indices, len(b.Palette)); err != nil {
+		return fmt.Errorf("writing indices: %w", err)
+	}
+
+	if err := writeStatePalette(w, b.Palette); err != nil {
+		return fmt.Errorf("writing palette: %w", err)
+	}
+
+	return nil
+}
+
+// bitsPerBlock returns the smallest valid number of bits which can index a palette of the given size.
+func bitsPerBlock(paletteSize int) int {
+	for _, b := range validBitsPerBlock {
+		if 1<<b >= paletteSize {
+			return b
+		}
+	}
+
+	return validBitsPerBlock[len(validBitsPerBlock)-1]
+}
+
+// writeStateIndices packs the palette indices into 32 bit words, preceded by the bits per block and version byte. It is
+// the inverse of stateIndices.
+func writeStateIndices(w io.Writer, indices []int, paletteSize int) error {
+	if len(indices) != subChunkBlockCount {
+		return fmt.Errorf("expected %d indices: got %d", subChunkBlockCount, len(indices))
+	}
+
+	bits := bitsPerBlock(paletteSize)
+
+	// The lowest bit is the storage version, which is always 0 for save files
+	if err := writeLittleEndian(w, byte(bits<<1)); err != nil {
+		return fmt.Errorf("writing bits per block: %w", err)
+	}
+
+	blocksPerWord := 32 / bits
+	wordCount := int(math.Ceil(subChunkBlockCount / float64(blocksPerWord)))
+
+	i := 0
+
+	for n := 0; n < wordCount; n++ {
+		var word uint32
+
+		for b := 0; b < blocksPerWord && i < subChunkBlockCount; b++ {
+			word |= uint32(indices[i]) << (b * bits)
+			i++
+		}
+
+		if err := writeLittleEndian(w, word); err != nil {
+			return fmt.Errorf("writing word %d: %w", n, err)
+		}
+	}
+
+	return nil
+}
+
+// writeStatePalette writes the palette size followed by the palette entries as NBT. It is the inverse of statePalette.
+func writeStatePalette(w io.Writer, palette []nbt.NBTTag) error {
+	if err := writeLittleEndian(w, int32(len(palette))); err != nil {
+		return fmt.Errorf("writing palette size: %w", err)
+	}
+
+	for i, t := range palette {
+		if err := nbt.Write(w, t); err != nil {
+			return fmt.Errorf("writing palette entry %d: %w", i, err)
+		}
+	}
+
+	return nil
 }
 
 func readLittleEndian(r io.Reader, data interface{}) error {
 	return binary.Read(r, binary.ByteOrder(binary.LittleEndian), data)
 }
+
+func writeLittleEndian(w io.Writer, data interface{}) error {
+	return binary.Write(w, binary.ByteOrder(binary.LittleEndian), data)
+}
@@ -1,9 +1,12 @@
 package world
 
 import (
+	"bytes"
+	"errors"
 	"testing"
 
 	"github.com/danhale-git/mine/mock"
+	"github.com/danhale-git/mine/nbt"
 )
 
 func TestSubChunkVoxelToIndex(t *testing.T) {
@@ -11,7 +14,10 @@ func TestSubChunkVoxelToIndex(t *testing.T) {
 	for x := 0; x < 16; x++ {
 		for z := 0; z < 16; z++ {
 			for y := 0; y < 16; y++ {
-				converted := subChunkVoxelToIndex(x, y, z)
+				converted, err := subChunkVoxelToIndex(x, y, z)
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
 				if converted != i {
 					t.Fatalf("expected coordinate %d, %d, %d to have index %d but got: %d",
 						x, y, z, i, converted)
@@ -22,6 +28,12 @@ func TestSubChunkVoxelToIndex(t *testing.T) {
 	}
 }
 
+func TestSubChunkVoxelToIndexInvalid(t *testing.T) {
+	if _, err := subChunkVoxelToIndex(16, 0, -1); err == nil {
+		t.Errorf("expected error for coordinates outside of sub chunk")
+	}
+}
+
 func TestSubChunkIndexToVoxel(t *testing.T) {
 	i := 0
 	for x := 0; x < 16; x++ {
@@ -39,7 +51,7 @@ func TestSubChunkIndexToVoxel(t *testing.T) {
 }
 
 func TestNewSubChunk(t *testing.T) {
-	_, err := parseSubChunk(mock.SubChunkValue)
+	_, err := parseSubChunk(mock.SubChunkValue, 0)
 	if err != nil {
 		t.Errorf("unexpected error returned: %s", err)
 	}
@@ -58,3 +70,108 @@ func TestSubChunkBlocks(t *testing.T) {
 		t.Errorf("expected %d blocks state indices: got %d", subChunkBlockCount, len(indices))
 	}
 }
+
+func TestEncodeSubChunk(t *testing.T) {
+	s, err := parseSubChunk(mock.SubChunkValue, 0)
+	if err != nil {
+		t.Fatalf("unexpected error returned: %s", err)
+	}
+
+	data, err := encodeSubChunk(s)
+	if err != nil {
+		t.Fatalf("unexpected error returned: %s", err)
+	}
+
+	encoded, err := parseSubChunk(data, 0)
+	if err != nil {
+		t.Fatalf("unexpected error parsing encoded sub chunk: %s", err)
+	}
+
+	if len(encoded.Layers) != len(s.Layers) {
+		t.Fatalf("expected %d layers: got %d", len(s.Layers), len(encoded.Layers))
+	}
+
+	for l := range s.Layers {
+		for i := 0; i < subChunkBlockCount; i++ {
+			want, _ := s.blockState(l, i)
+			got, _ := encoded.blockState(l, i)
+			if want.BlockID() != got.BlockID() {
+				t.Fatalf("layer %d block %d changed after encoding: expected '%s': got '%s'",
+					l, i, want.BlockID(), got.BlockID())
+			}
+		}
+	}
+}
+
+func TestBitsPerBlock(t *testing.T) {
+	cases := map[int]int{1: 1, 2: 1, 3: 2, 5: 3, 16: 4, 33: 6, 64: 6, 65: 8, 257: 16}
+	for size, want := range cases {
+		if got := bitsPerBlock(size); got != want {
+			t.Errorf("palette size %d: expected %d bits per block: got %d", size, want, got)
+		}
+	}
+}
+
+func TestParseSubChunkVersion9(t *testing.T) {
+	data := mock.SubChunkValueVersion9(-4)
+
+	s, err := parseSubChunk(data, -4)
+	if err != nil {
+		t.Fatalf("unexpected error returned: %s", err)
+	}
+
+	if s.Y != -4 {
+		t.Errorf("expected y index -4: got %d", s.Y)
+	}
+
+	if _, err := parseSubChunk(data, 0); err == nil {
+		t.Errorf("expected error for y index not matching key")
+	}
+
+	encoded, err := encodeSubChunk(s)
+	if err != nil {
+		t.Fatalf("unexpected error returned: %s", err)
+	}
+
+	if encoded[0] != subChunkVersionYIndex || int8(encoded[2]) != -4 {
+		t.Errorf("expected version 9 with y index -4: got version %d with y index %d", encoded[0], int8(encoded[2]))
+	}
+}
+
+func TestWorldVoxelToSubChunk(t *testing.T) {
+	x, y, z := worldVoxelToSubChunk(-1, -16, 17)
+	if x != 15 || y != 0 || z != 1 {
+		t.Errorf("expected sub chunk coordinates 15 0 1: got %d %d %d", x, y, z)
+	}
+}
+
+func TestParseSubChunkErrors(t *testing.T) {
+	var version *UnsupportedVersionError
+	if _, err := parseSubChunk([]byte{7, 1}, 0); !errors.As(err, &version) || version.Version != 7 {
+		t.Errorf("expected *UnsupportedVersionError with version 7: got %v", err)
+	}
+
+	var corrupt *CorruptStorageError
+
+	if _, err := parseSubChunk([]byte{8, 0}, 0); !errors.As(err, &corrupt) {
+		t.Errorf("expected *CorruptStorageError for storage count 0: got %v", err)
+	}
+
+	truncated := mock.SubChunkValue[:100]
+	if _, err := parseSubChunk(truncated, 0); !errors.As(err, &corrupt) {
+		t.Errorf("expected *CorruptStorageError for truncated value: got %v", err)
+	} else if corrupt.Offset != int64(len(truncated)) {
+		t.Errorf("expected offset %d: got %d", len(truncated), corrupt.Offset)
+	}
+
+	// A palette entry holding a list whose length prefix is far longer than the value
+	huge := bytes.Buffer{}
+	huge.Write([]byte{subChunkVersionStorages, 1})
+	_ = writeStateIndices(&huge, make([]int, subChunkBlockCount), 1)
+	_ = writeLittleEndian(&huge, int32(1))
+	huge.Write([]byte{nbt.TagCompound, 0, 0, nbt.TagList, 1, 0, 'l', nbt.TagInt, 0xFF, 0xFF, 0xFF, 0x7F})
+
+	if _, err := parseSubChunk(huge.Bytes(), 0); !errors.As(err, &corrupt) {
+		t.Errorf("expected *CorruptStorageError for palette list longer than the value: got %v", err)
+	}
+}
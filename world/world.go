@@ -1,94 +1,434 @@
 package world
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"math"
+	"sync"
 
 	"github.com/danhale-git/mine/leveldb"
-	"github.com/midnightfreddie/McpeTool/world"
+	"github.com/danhale-git/mine/nbt"
 )
 
-const waterID = "minecraft:water"
+const (
+	waterID = "minecraft:water"
+	airID   = "minecraft:air"
+)
 
 // BlockAPI modifies block data.
 type BlockAPI interface {
 	GetBlock(x, y, z, dimension int) (Block, error)
+	SetBlock(x, y, z, dimension int, b Block) error
 }
 
-// LevelDB returns data from a leveldb database.
+// LevelDB reads and writes data in a leveldb database.
 type LevelDB interface {
+	// Get returns the value with the given key, or leveldb.ErrNotFound if there is none.
 	Get(key []byte) ([]byte, error)
+	Put(key, value []byte) error
+	Delete(key []byte) error
+
+	// Iterate calls fn with each key which starts with prefix, in key order, stopping if fn returns an error. A nil
+	// prefix iterates every key. fn must not keep or modify the key after it returns.
+	Iterate(prefix []byte, fn func(key []byte) error) error
+
+	// Close releases the database's files and lock.
+	Close() error
 }
 
+// World reads and writes blocks in a world database. It is safe for concurrent use.
 type World struct {
-	db        LevelDB
-	subChunks map[struct{ x, y, z, d int }]*subChunkData
+	db LevelDB
+
+	// mu guards cache, loading and pins. Sub chunk contents are guarded by their own lock.
+	mu      sync.Mutex
+	cache   *subChunkCache
+	loading map[struct{ x, y, z, d int }]*subChunkLoad
+	pins    map[struct{ x, y, z, d int }]*subChunkPin
+
+	// entityMu serialises changes to block entity, entity, actor and digest records, which are read, changed and
+	// written back whole.
+	entityMu sync.Mutex
+
+	// blockEntityMu guards blockEntities, the block entities of chunks read by GetBlockEntity indexed by position, and
+	// blockEntityLoads. A chunk's entry is dropped when its block entities are edited.
+	blockEntityMu    sync.Mutex
+	blockEntities    *blockEntityCache
+	blockEntityLoads map[struct{ x, z, d int }]*blockEntityLoad
+}
+
+// subChunkLoad is a sub chunk being read from the database. done is closed when sc and err are set. waiters is the
+// number of other calls waiting for the load, which are pinned with it.
+type subChunkLoad struct {
+	done    chan struct{}
+	sc      *subChunkData
+	err     error
+	waiters int
 }
 
+// subChunkPin is a sub chunk in use by refs calls. A pinned sub chunk is shared with every call for its origin until it
+// is released, even if it has been evicted from the cache, so concurrent edits are made to the same copy and none are
+// lost.
+type subChunkPin struct {
+	sc   *subChunkData
+	refs int
+}
+
+// New opens the world at the given path, with the DefaultCacheConfig. It must be closed with Close when it is no longer
+// used, to release the database lock.
 func New(path string) (*World, error) {
-	w := World{}
-	w.subChunks = make(map[struct{ x, y, z, d int }]*subChunkData)
-	l, err := world.OpenWorld(path)
+	db, err := openLevelDB(path)
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("opening world: %w", err)
+	}
+
+	return newWorld(db), nil
+}
+
+func newWorld(db LevelDB) *World {
+	return &World{
+		db:      db,
+		cache:   newSubChunkCache(DefaultCacheConfig),
+		loading: make(map[struct{ x, y, z, d int }]*subChunkLoad),
+		pins:    make(map[struct{ x, y, z, d int }]*subChunkPin),
+
+		blockEntities:    newBlockEntityCache(DefaultCacheConfig.MaxBlockEntityChunks),
+		blockEntityLoads: make(map[struct{ x, z, d int }]*blockEntityLoad),
+	}
+}
+
+// Close closes the world database. The World must not be used after it is closed.
+func (w *World) Close() error {
+	if err := w.db.Close(); err != nil {
+		return fmt.Errorf("closing world database: %w", err)
 	}
 
-	w.db = &l
+	return nil
+}
 
-	return &w, nil
+// IterateKeys calls fn with each key in the world database which starts with prefix, in key order, stopping if fn
+// returns an error. A nil prefix iterates every key. fn must not keep or modify the key after it returns.
+func (w *World) IterateKeys(prefix []byte, fn func(key []byte) error) error {
+	return w.db.Iterate(prefix, fn)
 }
 
-// TODO: Don't get the sub chunk from the DB every time, cache it
+// Value returns the raw value stored with the given key in the world database.
+func (w *World) Value(key []byte) ([]byte, error) {
+	value, err := w.db.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("getting value with key '%x': %w", key, err)
+	}
 
-// GetBlock returns the block at the given coordinates.
+	return value, nil
+}
+
+// GetBlock returns the block at the given coordinates. Its block entity is not read, use GetBlockWithEntity for that.
 func (w *World) GetBlock(x, y, z, dimension int) (Block, error) {
+	sc, voxelIndex, release, err := w.subChunkVoxel(x, y, z, dimension)
+	if err != nil {
+		return Block{}, err
+	}
+	defer release()
+
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+
+	return subChunkBlock(sc, voxelIndex, x, y, z), nil
+}
+
+// GetBlockWithEntity returns the block at the given coordinates as GetBlock does, with its block entity if it has one.
+// It returns a *CorruptBlockEntitiesError if the chunk's block entities can't be parsed.
+func (w *World) GetBlockWithEntity(x, y, z, dimension int) (Block, error) {
+	b, err := w.GetBlock(x, y, z, dimension)
+	if err != nil {
+		return Block{}, err
+	}
+
+	if b.Entity, err = w.GetBlockEntity(x, y, z, dimension); err != nil {
+		return Block{}, err
+	}
+
+	return b, nil
+}
+
+// GetBlockLayers returns the block in each storage layer at the given coordinates. The first block is the one returned
+// by GetBlock. Further layers usually hold water for water logged blocks, but may hold other liquids or snow.
+func (w *World) GetBlockLayers(x, y, z, dimension int) ([]Block, error) {
+	sc, voxelIndex, release, err := w.subChunkVoxel(x, y, z, dimension)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+
+	blocks := make([]Block, len(sc.Layers))
+
+	for i := range blocks {
+		state, _ := sc.blockState(i, voxelIndex)
+		blocks[i] = newBlock(state, x, y, z)
+	}
+
+	return blocks, nil
+}
+
+// SetBlock sets the block at the given coordinates and writes the containing sub chunk back to the database. The
+// coordinates of the given block are ignored.
+//
+// If the block has a Liquid it is written to the second storage layer. Otherwise, if the block is WaterLogged, water is
+// written to the second storage layer. If it has neither, any existing second layer is set to air at these coordinates.
+func (w *World) SetBlock(x, y, z, dimension int, b Block) error {
+	sc, voxelIndex, release, err := w.subChunkVoxel(x, y, z, dimension)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	p, err := sc.blockPaletteIndices(b)
+	if err != nil {
+		return err
+	}
+
+	sc.setBlockIndices(voxelIndex, p)
+
+	return w.putSubChunk(sc, x, y, z, dimension)
+}
+
+// SetBlockLayer sets the block in the given storage layer at the given coordinates and writes the containing sub chunk
+// back to the database. Layer 0 holds the block returned by GetBlock. Missing layers are added and filled with air.
+// The coordinates of the given block are ignored.
+func (w *World) SetBlockLayer(x, y, z, dimension, layer int, b Block) error {
+	if layer < 0 || layer > math.MaxInt8 {
+		return fmt.Errorf("invalid storage layer %d", layer)
+	}
+
+	sc, voxelIndex, release, err := w.subChunkVoxel(x, y, z, dimension)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if err := sc.setBlockState(layer, voxelIndex, b.ID, b.States, b.Version); err != nil {
+		return err
+	}
+
+	return w.putSubChunk(sc, x, y, z, dimension)
+}
+
+// subChunkBlock returns the block at the given storage index in the sub chunk, with its Liquid from the second layer.
+func subChunkBlock(sc *subChunkData, voxelIndex, x, y, z int) Block {
+	state, _ := sc.blockState(0, voxelIndex)
+	b := newBlock(state, x, y, z)
+
+	if liquid, ok := sc.blockState(1, voxelIndex); ok && liquid.BlockID() != airID {
+		l := newBlock(liquid, x, y, z)
+		b.Liquid = &l
+		b.WaterLogged = l.ID == waterID
+	}
+
+	return b
+}
+
+// newBlock returns a block with the id, states and version of the given palette entry.
+func newBlock(state *nbt.NBTTag, x, y, z int) Block {
+	version, _ := state.BlockVersion()
+
+	return Block{
+		ID:      state.BlockID(),
+		States:  state.BlockStates(),
+		Version: version,
+		X:       x, Y: y, Z: z,
+	}
+}
+
+// putSubChunk encodes the sub chunk containing the given coordinates and writes it to the database. The caller must
+// hold the sub chunk's write lock.
+func (w *World) putSubChunk(sc *subChunkData, x, y, z, dimension int) error {
+	value, err := encodeSubChunk(sc)
+	if err != nil {
+		return fmt.Errorf("encoding sub chunk: %w", err)
+	}
+
+	key, err := leveldb.SubChunkKey(x, y, z, dimension)
+	if err != nil {
+		return fmt.Errorf("getting sub chunk key: %w", err)
+	}
+
+	if err := w.db.Put(key, value); err != nil {
+		return fmt.Errorf("putting sub chunk with key '%x': %w", key, err)
+	}
+
+	return nil
+}
+
+// subChunkVoxel returns the sub chunk containing the given coordinates and the block storage index of the coordinates
+// within it. release must be called when the sub chunk is no longer used, as described by subChunk.
+func (w *World) subChunkVoxel(x, y, z, dimension int) (*subChunkData, int, func(), error) {
+	voxelIndex, err := subChunkVoxelToIndex(worldVoxelToSubChunk(x, y, z))
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	sc, release, err := w.subChunk(x, y, z, dimension)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	return sc, voxelIndex, release, nil
+}
+
+// subChunk returns the parsed sub chunk containing the given coordinates, reading it from the database if it is not
+// cached. If several goroutines request the same sub chunk while it is being read, it is only read once.
+//
+// The sub chunk is pinned until release is called, so every call for the same origin gets the same copy while it is in
+// use. Changes must be written back to the database before release is called.
+func (w *World) subChunk(x, y, z, dimension int) (*subChunkData, func(), error) {
 	origin := subChunkOrigin(x, y, z, dimension)
+	release := func() { w.release(origin) }
 
-	var sc *subChunkData
-	var ok bool
+	w.mu.Lock()
 
-	if sc, ok = w.subChunks[origin]; !ok {
-		key, err := leveldb.SubChunkKey(
-			x, y, z,
-			dimension,
-		)
+	if sc, ok := w.cache.get(origin); ok {
+		w.cache.stats.Hits++
+		w.pin(origin, sc)
+		w.mu.Unlock()
+		return sc, release, nil
+	}
 
-		value, err := w.db.Get(key)
-		if err != nil {
+	// A pinned sub chunk which has been evicted is still in memory
+	if p, ok := w.pins[origin]; ok {
+		w.cache.stats.Hits++
+		p.refs++
+		w.mu.Unlock()
+		return p.sc, release, nil
+	}
 
-			// TODO: Make a PR to give this error a type - https://github.com/midnightfreddie/goleveldb/blob/fb12d34a9c1f2c7615bb9b258d09400cd315502f/leveldb/errors/errors.go#L19
+	w.cache.stats.Misses++
 
-			if err.Error() == "leveldb: not found" {
-				return Block{}, &SubChunkNotSavedError{origin}
-			}
-			return Block{}, fmt.Errorf("getting sub chunk with key '%x': %w", key, err)
-		}
+	if l, ok := w.loading[origin]; ok {
+		l.waiters++
+		w.mu.Unlock()
 
-		sc, err = parseSubChunk(value)
-		if err != nil {
-			return Block{}, fmt.Errorf("decoding sub chunk value: %w", err)
+		<-l.done
+		if l.err != nil {
+			return nil, nil, l.err
 		}
+		return l.sc, release, nil
+	}
+
+	l := &subChunkLoad{done: make(chan struct{})}
+	w.loading[origin] = l
 
-		w.subChunks[origin] = sc
+	w.mu.Unlock()
+
+	l.sc, l.err = w.readSubChunk(x, y, z, dimension)
+
+	w.mu.Lock()
+
+	if l.err == nil {
+		w.cache.add(origin, l.sc, l.sc.memorySize())
+		w.pins[origin] = &subChunkPin{sc: l.sc, refs: 1 + l.waiters}
 	}
+	delete(w.loading, origin)
 
-	voxelIndex := subChunkVoxelToIndex(worldVoxelToSubChunk(x, y, z))
+	w.mu.Unlock()
 
-	blockIndex := sc.Blocks.Indices[voxelIndex]
-	blockID := sc.Blocks.Palette[blockIndex].BlockID()
+	close(l.done)
 
-	waterLogged := false
-	if len(sc.WaterLogged.Indices) > 0 && len(sc.WaterLogged.Indices) >= voxelIndex {
-		waterIndex := sc.WaterLogged.Indices[voxelIndex]
-		blockID := sc.WaterLogged.Palette[waterIndex].BlockID()
-		waterLogged = blockID == waterID
+	if l.err != nil {
+		return nil, nil, l.err
 	}
 
-	return Block{
-		ID: blockID,
-		X:  x, Y: y, Z: z,
-		waterLogged: waterLogged,
-	}, nil
+	return l.sc, release, nil
+}
+
+// pinCreated pins a sub chunk created for the given origin because it is not saved, and returns it with its release
+// function. If another call has already pinned or cached a sub chunk for the origin, that is returned instead.
+func (w *World) pinCreated(origin struct{ x, y, z, d int }, created *subChunkData) (*subChunkData, func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	sc := created
+	if p, ok := w.pins[origin]; ok {
+		sc = p.sc
+	} else if cached, ok := w.cache.get(origin); ok {
+		sc = cached
+	}
+
+	w.pin(origin, sc)
+
+	return sc, func() { w.release(origin) }
+}
+
+// pin adds a reference to the pinned sub chunk with the given origin, pinning sc if it is not pinned. The caller must
+// hold w.mu.
+func (w *World) pin(origin struct{ x, y, z, d int }, sc *subChunkData) {
+	if p, ok := w.pins[origin]; ok {
+		p.refs++
+		return
+	}
+
+	w.pins[origin] = &subChunkPin{sc: sc, refs: 1}
+}
+
+// release removes a reference to the pinned sub chunk with the given origin, unpinning it when it is no longer used.
+func (w *World) release(origin struct{ x, y, z, d int }) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	p, ok := w.pins[origin]
+	if !ok {
+		return
+	}
+
+	if p.refs--; p.refs <= 0 {
+		delete(w.pins, origin)
+	}
+}
+
+// readSubChunk reads and parses the sub chunk containing the given coordinates from the database.
+func (w *World) readSubChunk(x, y, z, dimension int) (*subChunkData, error) {
+	origin := subChunkOrigin(x, y, z, dimension)
+
+	key, err := leveldb.SubChunkKey(
+		x, y, z,
+		dimension,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("getting sub chunk key: %w", err)
+	}
+
+	value, err := w.db.Get(key)
+	if err != nil {
+		if errors.Is(err, leveldb.ErrNotFound) {
+			return nil, &SubChunkNotSavedError{origin}
+		}
+		return nil, fmt.Errorf("getting sub chunk with key '%x': %w", key, err)
+	}
+
+	sc, err := parseSubChunk(value, origin.y)
+	if err != nil {
+		var version *UnsupportedVersionError
+		var corrupt *CorruptStorageError
+
+		switch {
+		case errors.As(err, &version):
+			version.Key = key
+		case errors.As(err, &corrupt):
+			corrupt.Key = key
+		}
+
+		return nil, fmt.Errorf("decoding sub chunk value: %w", err)
+	}
+
+	return sc, nil
 }
 
 // SubChunkNotSavedError is returned if a requested sub chunk is not present in the world database.
@@ -96,11 +436,9 @@ type SubChunkNotSavedError struct {
 	origin struct{ x, y, z, d int }
 }
 
-// TODO: State the dimension in the error message, when dimensions are supported
-
 func (e *SubChunkNotSavedError) Error() string {
-	return fmt.Sprintf("chunk with origin %d %d %d is not stored in this world database",
-		e.origin.x, e.origin.y, e.origin.z)
+	return fmt.Sprintf("chunk with origin %d %d %d in dimension %d is not stored in this world database",
+		e.origin.x, e.origin.y, e.origin.z, e.origin.d)
 }
 
 // Is implements Is(error) to support errors.Is()
@@ -1,13 +1,17 @@
 package world
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
+	"github.com/danhale-git/mine/leveldb"
 	"github.com/danhale-git/mine/mock"
 )
 
@@ -53,15 +57,16 @@ func BenchmarkGetBlock(b *testing.B) {
 }
 
 func TestGetBlock(t *testing.T) {
-	w := World{
-		db:        mock.ValidLevelDB(),
-		subChunks: make(map[struct{ x, y, z, d int }]*subChunkData),
-	}
+	w := newWorld(mock.ValidLevelDB())
+
+	const version = 17879555
+	noStates := map[string]interface{}{}
 
 	expected := []Block{
-		{Y: 0, ID: "minecraft:crimson_planks", waterLogged: false, X: 0, Z: 0},
-		{Y: 1, ID: "minecraft:fence", waterLogged: true, X: 0, Z: 0},
-		{Y: 2, ID: "minecraft:air", waterLogged: false, X: 0, Z: 0},
+		{Y: 0, ID: "minecraft:crimson_planks", States: noStates, Version: version, WaterLogged: false, X: 0, Z: 0},
+		{Y: 1, ID: "minecraft:fence", States: map[string]interface{}{"wood_type": "oak"}, Version: version, WaterLogged: true, X: 0, Z: 0,
+			Liquid: &Block{Y: 1, ID: waterID, States: map[string]interface{}{"liquid_depth": int32(0)}, Version: version}},
+		{Y: 2, ID: "minecraft:air", States: noStates, Version: version, WaterLogged: false, X: 0, Z: 0},
 	}
 
 	for y := 0; y < 3; y++ {
@@ -70,8 +75,128 @@ func TestGetBlock(t *testing.T) {
 			t.Fatalf("unexpected error: %s", err)
 		}
 
-		if b != expected[y] {
+		if !reflect.DeepEqual(b, expected[y]) {
 			t.Errorf("block did not match expected values: expected %+v: got %+v", expected[y], b)
 		}
 	}
 }
+
+func TestSetBlock(t *testing.T) {
+	db := mock.ValidLevelDB()
+	w := newWorld(db)
+
+	unmodified, err := w.GetBlock(1, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	set := []Block{
+		{ID: "minecraft:stone", States: map[string]interface{}{"stone_type": "granite"}},
+		{ID: "minecraft:fence", States: map[string]interface{}{"wood_type": "oak"}, WaterLogged: false},
+		{ID: "minecraft:fence", States: map[string]interface{}{"wood_type": "birch"}, WaterLogged: true},
+		{ID: "minecraft:fence", States: map[string]interface{}{"wood_type": "birch"}, WaterLogged: false,
+			Liquid: &Block{ID: "minecraft:lava", States: map[string]interface{}{"liquid_depth": int32(0)}}},
+	}
+
+	for y, b := range set {
+		if err := w.SetBlock(0, y, 0, 0, b); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	// Read the written sub chunk back from the database rather than the cache
+	w = newWorld(db)
+
+	for y, want := range set {
+		b, err := w.GetBlock(0, y, 0, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if b.ID != want.ID || b.WaterLogged != want.WaterLogged || !reflect.DeepEqual(b.States, want.States) {
+			t.Errorf("block did not match expected values: expected %+v: got %+v", want, b)
+		}
+
+		if (b.Liquid == nil) != (want.Liquid == nil && !want.WaterLogged) {
+			t.Errorf("unexpected liquid for block %d: %+v", y, b.Liquid)
+		} else if want.Liquid != nil && b.Liquid.ID != want.Liquid.ID {
+			t.Errorf("expected liquid '%s': got '%s'", want.Liquid.ID, b.Liquid.ID)
+		}
+	}
+
+	b, err := w.GetBlock(1, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !reflect.DeepEqual(b, unmodified) {
+		t.Errorf("unmodified block changed: expected %+v: got %+v", unmodified, b)
+	}
+}
+
+func TestGetBlockCorrupt(t *testing.T) {
+	db := mock.LevelDBWithKeys()
+	key, _ := leveldb.SubChunkKey(0, 0, 0, 0)
+	_ = db.Put(key, []byte{8, 1, 8})
+
+	w := newWorld(db)
+
+	var corrupt *CorruptStorageError
+
+	_, err := w.GetBlock(0, 0, 0, 0)
+	if !errors.As(err, &corrupt) {
+		t.Fatalf("expected *CorruptStorageError: got %v", err)
+	}
+
+	if !bytes.Equal(corrupt.Key, key) {
+		t.Errorf("expected error key '%x': got '%x'", key, corrupt.Key)
+	}
+
+	if _, err := w.GetBlock(0, 16, 0, 0); !errors.Is(err, &SubChunkNotSavedError{}) {
+		t.Errorf("expected *SubChunkNotSavedError: got %v", err)
+	}
+}
+
+func TestBlockLayers(t *testing.T) {
+	db := mock.ValidLevelDB()
+	w := newWorld(db)
+
+	snow := Block{ID: "minecraft:snow_layer", States: map[string]interface{}{"height": int32(2), "covered_bit": int8(0)}}
+
+	if err := w.SetBlockLayer(0, 0, 0, 0, 2, snow); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Read the written sub chunk back from the database rather than the cache
+	w = newWorld(db)
+
+	layers, err := w.GetBlockLayers(0, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(layers) != 3 {
+		t.Fatalf("expected 3 layers: got %d", len(layers))
+	}
+
+	expected := []string{"minecraft:crimson_planks", airID, "minecraft:snow_layer"}
+	for i, b := range layers {
+		if b.ID != expected[i] {
+			t.Errorf("expected layer %d to be '%s': got '%s'", i, expected[i], b.ID)
+		}
+	}
+
+	if !reflect.DeepEqual(layers[2].States, snow.States) {
+		t.Errorf("unexpected states: expected %+v: got %+v", snow.States, layers[2].States)
+	}
+
+	// Blocks without a snow layer should have air in the new layer
+	layers, err = w.GetBlockLayers(0, 1, 0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if layers[1].ID != waterID || layers[2].ID != airID {
+		t.Errorf("expected water and air in layers 1 and 2: got %+v", layers)
+	}
+}
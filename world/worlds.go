@@ -0,0 +1,171 @@
+package world
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// levelNameFile is the name of the file in a world directory holding the world's display name.
+const levelNameFile = "levelname.txt"
+
+// GameMode is the default game mode of a world.
+type GameMode int32
+
+// Game modes as stored in level.dat
+const (
+	Survival  GameMode = 0
+	Creative  GameMode = 1
+	Adventure GameMode = 2
+	Spectator GameMode = 6
+)
+
+func (g GameMode) String() string {
+	switch g {
+	case Survival:
+		return "survival"
+	case Creative:
+		return "creative"
+	case Adventure:
+		return "adventure"
+	case Spectator:
+		return "spectator"
+	}
+
+	return fmt.Sprintf("unknown (%d)", int32(g))
+}
+
+// WorldInfo describes a world directory in a minecraftWorlds directory.
+type WorldInfo struct {
+	Dir  string // The directory name, e.g. '97caYQjdAgA='
+	Path string // The full path of the world directory, which can be passed to New
+
+	// Name is the display name from levelname.txt, or from level.dat if levelname.txt is missing.
+	Name string
+
+	GameMode   GameMode
+	LastPlayed time.Time
+
+	// Err is the first error reading levelname.txt or level.dat. If level.dat couldn't be read GameMode and LastPlayed
+	// are not set.
+	Err error
+}
+
+// ListWorlds returns the worlds in the given minecraftWorlds directory, most recently played first. Directories with no
+// 'db' folder are ignored. A world whose files can't be read is still listed, with the error in its Err field.
+func ListWorlds(dir string) ([]WorldInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading worlds directory: %w", err)
+	}
+
+	worlds := make([]WorldInfo, 0, len(entries))
+
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(dir, e.Name())
+
+		if info, err := os.Stat(filepath.Join(path, "db")); err != nil || !info.IsDir() {
+			continue
+		}
+
+		worlds = append(worlds, readWorldInfo(path))
+	}
+
+	sort.SliceStable(worlds, func(i, j int) bool {
+		return worlds[i].LastPlayed.After(worlds[j].LastPlayed)
+	})
+
+	return worlds, nil
+}
+
+// FindWorld returns the world in the given minecraftWorlds directory whose display name or directory name matches name.
+// Display names are compared ignoring case. It is an error if more than one world has the display name.
+func FindWorld(dir, name string) (WorldInfo, error) {
+	worlds, err := ListWorlds(dir)
+	if err != nil {
+		return WorldInfo{}, err
+	}
+
+	found := make([]WorldInfo, 0)
+
+	for _, w := range worlds {
+		if w.Dir == name {
+			return w, nil
+		}
+
+		if strings.EqualFold(w.Name, name) {
+			found = append(found, w)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return WorldInfo{}, fmt.Errorf("no world named '%s' in '%s'", name, dir)
+	case 1:
+		return found[0], nil
+	}
+
+	dirs := make([]string, len(found))
+	for i, w := range found {
+		dirs[i] = w.Dir
+	}
+
+	return WorldInfo{}, fmt.Errorf("%d worlds are named '%s', use a directory name instead: %s",
+		len(found), name, strings.Join(dirs, ", "))
+}
+
+// readWorldInfo reads the description of the world directory at the given path.
+func readWorldInfo(path string) WorldInfo {
+	w := WorldInfo{Dir: filepath.Base(path), Path: path}
+
+	name, err := os.ReadFile(filepath.Join(path, levelNameFile))
+	if err != nil && !os.IsNotExist(err) {
+		w.Err = fmt.Errorf("reading %s: %w", levelNameFile, err)
+	}
+
+	w.Name = strings.TrimSpace(string(name))
+
+	l, err := ReadLevelData(path)
+	if err != nil {
+		if w.Err == nil {
+			w.Err = err
+		}
+		return w
+	}
+
+	if w.Name == "" {
+		w.Name = l.LevelName()
+	}
+
+	w.GameMode = l.GameMode()
+	w.LastPlayed = l.LastPlayed()
+
+	return w
+}
+
+// WorldSize returns the total size in bytes of all files in the world directory at the given path. It walks the whole
+// directory, so it is not read by ListWorlds.
+func WorldSize(path string) (int64, error) {
+	var size int64
+
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			size += info.Size()
+		}
+
+		return nil
+	})
+
+	return size, err
+}
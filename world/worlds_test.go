@@ -0,0 +1,107 @@
+package world
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/danhale-git/mine/nbt"
+)
+
+// writeTestWorld creates a world directory with the given display name and level.dat fields.
+func writeTestWorld(t *testing.T, dir, name string, mode GameMode, lastPlayed int64) {
+	path := filepath.Join(dir, name+"=")
+
+	if err := os.MkdirAll(filepath.Join(path, "db"), 0755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(path, levelNameFile), []byte(name+"\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	root := nbt.NBTTag{Type: nbt.TagCompound, Value: []nbt.NBTTag{
+		{Type: nbt.TagInt, Name: "GameType", Value: int32(mode)},
+		{Type: nbt.TagLong, Name: "LastPlayed", Value: lastPlayed},
+		{Type: nbt.TagString, Name: "LevelName", Value: name},
+	}}
+
+	l := &LevelData{StorageVersion: 9, Root: root}
+	if err := l.Write(path); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestListWorlds(t *testing.T) {
+	dir := t.TempDir()
+
+	writeTestWorld(t, dir, "Old", Survival, 1000)
+	writeTestWorld(t, dir, "New", Creative, 2000)
+
+	// Not a world
+	if err := os.Mkdir(filepath.Join(dir, "other"), 0755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// A world whose files can't be read is listed with its error
+	if err := os.MkdirAll(filepath.Join(dir, "broken", "db"), 0755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := os.Mkdir(filepath.Join(dir, "broken", levelNameFile), 0755); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	worlds, err := ListWorlds(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(worlds) != 3 {
+		t.Fatalf("expected 3 worlds: got %d", len(worlds))
+	}
+
+	w := worlds[0]
+
+	if w.Name != "New" || w.Dir != "New=" || w.GameMode != Creative || !w.LastPlayed.Equal(time.Unix(2000, 0)) {
+		t.Errorf("unexpected first world %+v", w)
+	}
+
+	if w.Err != nil {
+		t.Errorf("unexpected error: %s", w.Err)
+	}
+
+	if worlds[1].Name != "Old" {
+		t.Errorf("expected worlds to be ordered by last played: got %s second", worlds[1].Name)
+	}
+
+	if worlds[2].Dir != "broken" || worlds[2].Err == nil {
+		t.Errorf("expected broken world to be listed last with an error: got %+v", worlds[2])
+	}
+
+	if size, err := WorldSize(w.Path); err != nil || size == 0 {
+		t.Errorf("unexpected size %d or error %v", size, err)
+	}
+}
+
+func TestFindWorld(t *testing.T) {
+	dir := t.TempDir()
+
+	writeTestWorld(t, dir, "Castle", Creative, 1000)
+
+	for _, name := range []string{"castle", "Castle="} {
+		w, err := FindWorld(dir, name)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if w.Path != filepath.Join(dir, "Castle=") {
+			t.Errorf("unexpected path '%s'", w.Path)
+		}
+	}
+
+	if _, err := FindWorld(dir, "Village"); err == nil {
+		t.Errorf("expected error finding missing world")
+	}
+}